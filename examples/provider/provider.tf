@@ -8,7 +8,8 @@ terraform {
 }
 provider "koyeb" {
   #
-  # Use the KOYEB_TOKEN env variable to set your Koyeb API token.
+  # Use the KOYEB_TOKEN env variable to set your Koyeb API token,
+  # or set the token argument.
   #
 }
 
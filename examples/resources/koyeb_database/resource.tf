@@ -0,0 +1,20 @@
+resource "koyeb_app" "my-app" {
+  name = "my-app"
+}
+
+resource "koyeb_database" "my-database" {
+  name           = "my-database"
+  app_name       = koyeb_app.my-app.name
+  engine_version = 16
+  region         = "fra"
+  instance_type  = "small"
+
+  roles {
+    name = "admin"
+  }
+
+  databases {
+    name  = "orders"
+    owner = "admin"
+  }
+}
@@ -0,0 +1,232 @@
+package koyeb
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	_nethttp "net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+// alwaysIgnoredDirectories are never archived, like in the koyeb CLI.
+var alwaysIgnoredDirectories = []string{".git"}
+
+// walkArchive calls fn for each file and directory of the archive of path, in
+// lexical order, skipping the ones matching the ignore patterns. Patterns
+// use the filepath.Match syntax and are matched against the path relative to
+// the archived directory and against the base name.
+func walkArchive(path string, ignorePatterns []string, fn func(file string, name string, info fs.FileInfo) error) error {
+	basePath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(basePath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+
+	patterns := append(append([]string{}, alwaysIgnoredDirectories...), ignorePatterns...)
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+	}
+
+	return filepath.WalkDir(basePath, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file == basePath {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(basePath, file)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(relativePath)
+
+		if isIgnored(name, patterns) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return fn(file, name, info)
+	})
+}
+
+func isIgnored(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hashArchive returns a hash of the names, modes and contents of the files of
+// the archive of path. Unlike the archive itself, the hash doesn't depend on
+// the modification times, so it only changes when the content does.
+func hashArchive(path string, ignorePatterns []string) (string, error) {
+	hash := sha256.New()
+
+	err := walkArchive(path, ignorePatterns, func(file string, name string, info fs.FileInfo) error {
+		fmt.Fprintf(hash, "%s\x00%s\x00", name, info.Mode())
+
+		switch {
+		case info.Mode().IsRegular():
+			data, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer data.Close()
+
+			if _, err := io.Copy(hash, data); err != nil {
+				return err
+			}
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			io.WriteString(hash, target)
+		}
+		hash.Write([]byte{0})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeArchive writes the gzipped tarball of path to w.
+func writeArchive(w io.Writer, path string, ignorePatterns []string) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err := walkArchive(path, ignorePatterns, func(file string, name string, info fs.FileInfo) error {
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			link = target
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("unable to create header for file %s: %w", file, err)
+		}
+		header.Name = name
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("unable to write header for file %s: %w", file, err)
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			data, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer data.Close()
+
+			if _, err := io.Copy(tarWriter, data); err != nil {
+				return fmt.Errorf("unable to copy file %s into the archive: %w", file, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// uploadArchive archives path, uploads the archive to Koyeb and returns its
+// ID along with the hash of its content.
+func uploadArchive(ctx context.Context, client *koyeb.APIClient, path string, ignorePatterns []string) (string, string, error) {
+	hash, err := hashArchive(path, ignorePatterns)
+	if err != nil {
+		return "", "", err
+	}
+
+	tarball, err := os.CreateTemp("", "koyeb-archive-*.tar.gz")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(tarball.Name())
+	defer tarball.Close()
+
+	if err := writeArchive(tarball, path, ignorePatterns); err != nil {
+		return "", "", err
+	}
+
+	size, err := tarball.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", "", err
+	}
+
+	// The size is sent as a string since it can't be represented by a JSON
+	// number
+	res, _, err := client.ArchivesApi.CreateArchive(ctx).Archive(koyeb.CreateArchive{
+		Size: toOpt(fmt.Sprintf("%d", size)),
+	}).Execute()
+	if err != nil {
+		return "", "", err
+	}
+
+	if _, err := tarball.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+
+	// The upload URL is signed, so the request is sent without the API token
+	req, err := _nethttp.NewRequestWithContext(ctx, _nethttp.MethodPut, res.Archive.GetUploadUrl(), tarball)
+	if err != nil {
+		return "", "", err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("x-goog-content-length-range", fmt.Sprintf("%d,%d", size, size))
+
+	resp, err := _nethttp.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to upload the archive: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != _nethttp.StatusOK {
+		return "", "", fmt.Errorf("unable to upload the archive: the server returned HTTP %d", resp.StatusCode)
+	}
+
+	return res.Archive.GetId(), hash, nil
+}
@@ -0,0 +1,131 @@
+package koyeb
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readArchive(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(tarReader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(content)
+	}
+	return files
+}
+
+func TestWriteArchive(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.go":             "package main",
+		"app/handler.go":      "package app",
+		"app/handler_test.go": "package app",
+		".git/HEAD":           "ref: refs/heads/main",
+		"tmp/cache":           "cache",
+	})
+
+	var buf bytes.Buffer
+	if err := writeArchive(&buf, dir, []string{"*_test.go", "tmp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"main.go":        "package main",
+		"app/handler.go": "package app",
+	}
+	if files := readArchive(t, buf.Bytes()); !reflect.DeepEqual(files, expected) {
+		t.Fatalf("expected the archive to contain %v, got %v", expected, files)
+	}
+}
+
+func TestWriteArchive_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"main.go": "package main"})
+
+	if err := writeArchive(io.Discard, filepath.Join(dir, "missing"), nil); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+	if err := writeArchive(io.Discard, filepath.Join(dir, "main.go"), nil); err == nil {
+		t.Error("expected an error for a file")
+	}
+	if err := writeArchive(io.Discard, dir, []string{"["}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestHashArchive(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.go":   "package main",
+		"debug.log": "first",
+	})
+	ignorePatterns := []string{"*.log"}
+
+	hash := func() string {
+		t.Helper()
+		h, err := hashArchive(dir, ignorePatterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	first := hash()
+
+	// Neither the modification times nor the ignored files change the hash
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "main.go"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, dir, map[string]string{"debug.log": "second"})
+	if got := hash(); got != first {
+		t.Fatalf("expected the hash to be unchanged, got %s instead of %s", got, first)
+	}
+
+	writeTestFiles(t, dir, map[string]string{"main.go": "package main\n"})
+	if got := hash(); got == first {
+		t.Fatal("expected the hash to change with the content")
+	}
+}
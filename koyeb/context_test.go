@@ -0,0 +1,93 @@
+package koyeb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// interruptAfter returns a context canceled after the given delay, as when
+// Terraform is interrupted during an apply.
+func interruptAfter(t *testing.T, delay time.Duration) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	time.AfterFunc(delay, cancel)
+	return ctx
+}
+
+func TestContext_InterruptsRequests(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	p := testConfiguredProvider(t, server.URL, "token")
+	app := resourceKoyebApp()
+	d := schema.TestResourceDataRaw(t, app.Schema, map[string]interface{}{
+		"name": "my-app",
+	})
+
+	start := time.Now()
+	diags := app.CreateContext(interruptAfter(t, 200*time.Millisecond), d, p.Meta())
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the interrupted request to stop quickly, took %s", elapsed)
+	}
+	if !diags.HasError() {
+		t.Fatal("expected an error when the request is interrupted")
+	}
+}
+
+func TestContext_InterruptsPolling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/services":
+			w.Write([]byte(`{"service":{"id":"service-id","name":"main","latest_deployment_id":"deployment-id"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/deployments/deployment-id":
+			w.Write([]byte(`{"deployment":{"id":"deployment-id","status":"STARTING"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status":404,"code":"not_found","message":"Not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	p := testConfiguredProvider(t, server.URL, "token")
+	service := resourceKoyebService()
+	d := schema.TestResourceDataRaw(t, service.Schema, map[string]interface{}{
+		"definition": []interface{}{
+			map[string]interface{}{
+				"name":    "main",
+				"regions": []interface{}{"fra"},
+				"docker": []interface{}{
+					map[string]interface{}{"image": "koyeb/demo"},
+				},
+				"instance_types": []interface{}{
+					map[string]interface{}{"type": "nano"},
+				},
+			},
+		},
+	})
+
+	start := time.Now()
+	diags := service.CreateContext(interruptAfter(t, 500*time.Millisecond), d, p.Meta())
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the interrupted wait to stop quickly, took %s", elapsed)
+	}
+	if !diags.HasError() {
+		t.Fatal("expected an error when the wait is interrupted")
+	}
+	if d.Id() != "service-id" {
+		t.Fatal("expected the created service to be kept in the state")
+	}
+}
@@ -83,7 +83,7 @@ func testAccCheckDataSourceKoyebAppExists(n string, app *koyeb.App) resource.Tes
 			return fmt.Errorf("No Record ID is set")
 		}
 
-		client := testAccProvider.Meta().(*koyeb.APIClient)
+		client := testAccProvider.Meta().(*providerMeta).client
 
 		res, _, err := client.AppsApi.GetApp(context.Background(), rs.Primary.ID).Execute()
 
@@ -5,8 +5,6 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
-	"github.com/koyeb/koyeb-cli/pkg/koyeb/idmapper"
 )
 
 func dataSourceKoyebDomain() *schema.Resource {
@@ -17,12 +15,9 @@ func dataSourceKoyebDomain() *schema.Resource {
 }
 
 func dataSourceKoyebDomainRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	resolver := meta.(*providerMeta).resolver
 
-	mapper := idmapper.NewMapper(context.Background(), client)
-	domainMapper := mapper.Domain()
-
-	id, err := domainMapper.ResolveID(d.Get("name").(string))
+	id, err := resolver.ResolveID(ctx, domainKind, d.Get("name").(string))
 
 	if err != nil {
 		return diag.Errorf("Error retrieving domain: %s", err)
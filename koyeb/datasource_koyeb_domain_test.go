@@ -78,7 +78,7 @@ func testAccCheckDataSourceKoyebDomainExists(n string, domain *koyeb.Domain) res
 			return fmt.Errorf("No Record ID is set")
 		}
 
-		client := testAccProvider.Meta().(*koyeb.APIClient)
+		client := testAccProvider.Meta().(*providerMeta).client
 
 		res, _, err := client.DomainsApi.GetDomain(context.Background(), rs.Primary.ID).Execute()
 
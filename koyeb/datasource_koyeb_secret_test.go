@@ -72,7 +72,7 @@ func testAccCheckDataSourceKoyebSecretExists(n string, secret *koyeb.Secret) res
 			return fmt.Errorf("No Record ID is set")
 		}
 
-		client := testAccProvider.Meta().(*koyeb.APIClient)
+		client := testAccProvider.Meta().(*providerMeta).client
 
 		res, _, err := client.SecretsApi.GetSecret(context.Background(), rs.Primary.ID).Execute()
 
@@ -5,8 +5,6 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
-	"github.com/koyeb/koyeb-cli/pkg/koyeb/idmapper"
 )
 
 func dataSourceKoyebService() *schema.Resource {
@@ -54,6 +52,11 @@ func dataSourceKoyebService() *schema.Resource {
 				Computed:    true,
 				Description: "The service latest deployment id",
 			},
+			"git_sha": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The commit SHA deployed by the latest deployment, for services deployed from git",
+			},
 			"version": {
 				Type:        schema.TypeString,
 				Computed:    true,
@@ -70,6 +73,39 @@ func dataSourceKoyebService() *schema.Resource {
 				Optional:    true,
 				Description: "The status messages of the service",
 			},
+			"app_domain": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The domain automatically assigned to the app of the service",
+			},
+			"public_urls": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The public URLs of the service, one for each domain of its app and path of the routes of its active deployment",
+			},
+			"private_hostname": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The hostname of the service on the private network of the organization, to reach it from the other services. The API doesn't expose it, so it is derived from the names of the service and of its app, as `<service>.<app>.koyeb`",
+			},
+			"regional_deployments": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The regional deployments of the active deployment of the service, one for each region",
+				Elem:        regionalDeploymentSchema(),
+			},
+			"instances": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The instances of the active deployment of the service which are not stopped",
+				Elem:        instanceSchema(),
+			},
+			"paused": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the service is paused",
+			},
 			"paused_at": {
 				Type:        schema.TypeString,
 				Computed:    true,
@@ -100,12 +136,9 @@ func dataSourceKoyebService() *schema.Resource {
 }
 
 func dataSourceKoyebServiceRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
-
-	mapper := idmapper.NewMapper(context.Background(), client)
-	serviceMapper := mapper.Service()
+	resolver := meta.(*providerMeta).resolver
 
-	id, err := serviceMapper.ResolveID(d.Get("slug").(string))
+	id, err := resolver.ResolveID(ctx, serviceKind, d.Get("slug").(string))
 
 	if err != nil {
 		return diag.Errorf("Error retrieving service: %s", err)
@@ -100,7 +100,7 @@ func testAccCheckDataSourceKoyebServiceExists(n string, service *koyeb.Service)
 			return fmt.Errorf("No Record ID is set")
 		}
 
-		client := testAccProvider.Meta().(*koyeb.APIClient)
+		client := testAccProvider.Meta().(*providerMeta).client
 
 		res, _, err := client.ServicesApi.GetService(context.Background(), rs.Primary.ID).Execute()
 
@@ -0,0 +1,91 @@
+package koyeb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func deletionProtectionSchema(kind string) *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: fmt.Sprintf("If set to true, the %s can't be deleted or replaced. It must be set to false and applied before the %s can be deleted", kind, kind),
+	}
+}
+
+// checkDeletionProtection refuses to delete a protected object. The attribute
+// is only stored in the state, so it is read from the state being destroyed.
+func checkDeletionProtection(d *schema.ResourceData, kind string) diag.Diagnostics {
+	if !d.Get("deletion_protection").(bool) {
+		return nil
+	}
+
+	return diag.Diagnostics{
+		{
+			Severity:      diag.Error,
+			Summary:       fmt.Sprintf("The %s %s is protected against deletion", kind, d.Id()),
+			Detail:        fmt.Sprintf("Set deletion_protection to false and apply the change before deleting the %s.", kind),
+			AttributePath: cty.GetAttrPath("deletion_protection"),
+		},
+	}
+}
+
+// customizeDeletionProtectionDiff returns a CustomizeDiffFunc failing the plans
+// which replace a protected object, since the replacement starts by deleting
+// it.
+func customizeDeletionProtectionDiff(kind string, resourceSchema map[string]*schema.Schema) schema.CustomizeDiffFunc {
+	return func(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+		if d.Id() == "" {
+			return nil
+		}
+		if protected, _ := d.GetChange("deletion_protection"); !protected.(bool) {
+			return nil
+		}
+
+		replaced := []string{}
+		for _, key := range d.GetChangedKeysPrefix("") {
+			if forcesNew(resourceSchema, strings.Split(key, ".")) && d.HasChange(key) {
+				replaced = append(replaced, key)
+			}
+		}
+		if len(replaced) == 0 {
+			return nil
+		}
+		sort.Strings(replaced)
+
+		return fmt.Errorf("the %s %s is protected against deletion and can't be replaced because of the change of %s: set deletion_protection to false and apply the change before replacing it", kind, d.Id(), strings.Join(replaced, ", "))
+	}
+}
+
+// forcesNew returns whether the attribute at path, or one of the attributes
+// containing it, is ForceNew.
+func forcesNew(resourceSchema map[string]*schema.Schema, path []string) bool {
+	s, ok := resourceSchema[path[0]]
+	if !ok {
+		return false
+	}
+	if s.ForceNew {
+		return true
+	}
+
+	resource, ok := s.Elem.(*schema.Resource)
+	if !ok || len(path) < 3 {
+		return false
+	}
+	// Skip the index of the list or set element
+	return forcesNew(resource.Schema, path[2:])
+}
+
+// importStateWithDeletionProtection imports an object from its ID, with the
+// deletion protection disabled like for the objects created without it.
+func importStateWithDeletionProtection(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	d.Set("deletion_protection", false)
+	return []*schema.ResourceData{d}, nil
+}
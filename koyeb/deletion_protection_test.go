@@ -0,0 +1,232 @@
+package koyeb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+// testAccDeletionProtectionSteps returns the steps checking that the object
+// of the resource addr is protected against its deletion and its replacement,
+// which changes the attribute replace. config returns the configuration of
+// the object, which differs in place when updated and forces its replacement
+// when replaced. The last step disables the protection, so that the object
+// can be destroyed.
+func testAccDeletionProtectionSteps(addr string, replace string, config func(updated bool, replaced bool, deletionProtection bool) string, check resource.TestCheckFunc) []resource.TestStep {
+	replaceError := regexp.MustCompile("can't be replaced because of the change of " + regexp.QuoteMeta(replace))
+
+	return []resource.TestStep{
+		{
+			Config: config(false, false, true),
+			Check: resource.ComposeTestCheckFunc(
+				check,
+				resource.TestCheckResourceAttr(addr, "deletion_protection", "true"),
+			),
+		},
+		{
+			Config:      config(false, false, true),
+			Destroy:     true,
+			ExpectError: regexp.MustCompile("protected against deletion"),
+		},
+		{
+			Config:      config(false, true, true),
+			ExpectError: replaceError,
+		},
+		{
+			// Disabling the protection in the same plan doesn't help, the
+			// prior state is used to delete the object
+			Config:      config(false, true, false),
+			ExpectError: replaceError,
+		},
+		{
+			Config: config(true, false, true),
+			Check:  check,
+		},
+		{
+			Config: config(true, false, false),
+			Check: resource.ComposeTestCheckFunc(
+				check,
+				resource.TestCheckResourceAttr(addr, "deletion_protection", "false"),
+			),
+		},
+	}
+}
+
+func TestAccKoyebApp_DeletionProtection(t *testing.T) {
+	var app koyeb.App
+	appName := randomTestName()
+	otherAppName := randomTestName()
+
+	config := func(updated bool, replaced bool, deletionProtection bool) string {
+		if replaced {
+			return fmt.Sprintf(testAccCheckKoyebAppConfig_deletion_protection, otherAppName, deletionProtection)
+		}
+		return fmt.Sprintf(testAccCheckKoyebAppConfig_deletion_protection, appName, deletionProtection)
+	}
+	steps := testAccDeletionProtectionSteps("koyeb_app.foo", "name", config, testAccCheckKoyebAppExists("koyeb_app.foo", &app))
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebAppDestroy,
+		Steps: append(steps, resource.TestStep{
+			// Imported objects aren't protected
+			ResourceName:      "koyeb_app.foo",
+			ImportState:       true,
+			ImportStateVerify: true,
+		}),
+	})
+}
+
+func TestAccKoyebService_DeletionProtection(t *testing.T) {
+	var service koyeb.Service
+	var deployment koyeb.Deployment
+	appName := randomTestName()
+
+	config := func(updated bool, replaced bool, deletionProtection bool) string {
+		name, image := "service", "koyeb/demo"
+		if replaced {
+			name = "other"
+		}
+		if updated {
+			image = "koyeb/demo:latest"
+		}
+		return fmt.Sprintf(testAccCheckKoyebServiceConfig_deletion_protection, appName, appName, deletionProtection, name, image)
+	}
+	steps := testAccDeletionProtectionSteps("koyeb_service.bar", "definition.0.name", config, testAccCheckKoyebServiceExists("koyeb_service.bar", &service))
+
+	// Changing the protection doesn't redeploy services
+	steps[4].Check = resource.ComposeTestCheckFunc(steps[4].Check, testAccCheckKoyebServiceLatestDeployment(&service, &deployment))
+	steps[5].Check = resource.ComposeTestCheckFunc(steps[5].Check, func(*terraform.State) error {
+		if service.GetLatestDeploymentId() != deployment.GetId() {
+			return fmt.Errorf("Service redeployed by the change of its deletion protection: %s", service.GetLatestDeploymentId())
+		}
+		return nil
+	})
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps:             steps,
+	})
+}
+
+func TestAccKoyebVolume_DeletionProtection(t *testing.T) {
+	var volume koyeb.PersistentVolume
+	volumeName := randomTestName()
+	renamedVolumeName := randomTestName()
+
+	config := func(updated bool, replaced bool, deletionProtection bool) string {
+		name, region := volumeName, "fra"
+		if updated {
+			name = renamedVolumeName
+		}
+		if replaced {
+			region = "was"
+		}
+		return fmt.Sprintf(testAccCheckKoyebVolumeConfig_deletion_protection, name, region, deletionProtection)
+	}
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebVolumeDestroy,
+		Steps:             testAccDeletionProtectionSteps("koyeb_volume.foo", "region", config, testAccCheckKoyebVolumeExists("koyeb_volume.foo", &volume)),
+	})
+}
+
+func TestAccKoyebSecret_DeletionProtection(t *testing.T) {
+	var secret koyeb.Secret
+	secretName := randomTestName()
+	otherSecretName := randomTestName()
+
+	config := func(updated bool, replaced bool, deletionProtection bool) string {
+		name, value := secretName, "foo"
+		if updated {
+			value = "bar"
+		}
+		if replaced {
+			name = otherSecretName
+		}
+		return fmt.Sprintf(testAccCheckKoyebSecretConfig_deletion_protection, name, value, deletionProtection)
+	}
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebSecretDestroy,
+		Steps:             testAccDeletionProtectionSteps("koyeb_secret.foo", "name", config, testAccCheckKoyebSecretExists("koyeb_secret.foo", &secret)),
+	})
+}
+
+func TestForcesNew(t *testing.T) {
+	s := serviceSchema()
+
+	for key, expected := range map[string]bool{
+		"app_name":                       true,
+		"definition.0.name":              true,
+		"definition.0.docker.1234.image": false,
+		"definition.#":                   false,
+		"deletion_protection":            false,
+		"unknown":                        false,
+	} {
+		if got := forcesNew(s, strings.Split(key, ".")); got != expected {
+			t.Errorf("expected forcesNew(%s) to be %v, got %v", key, expected, got)
+		}
+	}
+}
+
+const testAccCheckKoyebAppConfig_deletion_protection = `
+resource "koyeb_app" "foo" {
+	name                = "%s"
+	deletion_protection = %t
+}`
+
+const testAccCheckKoyebServiceConfig_deletion_protection = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
+
+resource "koyeb_service" "bar" {
+	app_name            = "%s"
+	deletion_protection = %t
+	definition {
+		name = "%s"
+		instance_types {
+		  type = "micro"
+		}
+		scalings {
+		  min = 1
+		  max = 1
+		}
+		regions = ["fra"]
+		docker {
+		  image = "%s"
+		}
+	}
+
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
+
+const testAccCheckKoyebVolumeConfig_deletion_protection = `
+resource "koyeb_volume" "foo" {
+	name                = "%s"
+	max_size            = 10
+	region              = "%s"
+	deletion_protection = %t
+}`
+
+const testAccCheckKoyebSecretConfig_deletion_protection = `
+resource "koyeb_secret" "foo" {
+	name                = "%s"
+	value               = "%s"
+	deletion_protection = %t
+}`
@@ -0,0 +1,142 @@
+package koyeb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	_nethttp "net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+// apiFieldAliases maps the top-level fields of the Koyeb API to the name of
+// the matching attribute when it differs, or when the attribute of the same
+// name is only computed.
+var apiFieldAliases = map[string]string{
+	"app_id":                 "app_name",
+	"digital_ocean_registry": "digital_ocean_container_registry",
+}
+
+// isNotFound returns whether the API responded with a 404. resp is nil when
+// the request failed before a response was received.
+func isNotFound(resp *_nethttp.Response) bool {
+	return resp != nil && resp.StatusCode == _nethttp.StatusNotFound
+}
+
+// apiErrorDiagnostics translates an error returned by the Koyeb API client
+// into diagnostics. When the API rejected some fields of the request, one
+// diagnostic is returned for each field, pointing to the matching attribute of
+// resourceSchema.
+func apiErrorDiagnostics(summary string, err error, resourceSchema map[string]*schema.Schema) diag.Diagnostics {
+	var apiErr *koyeb.GenericOpenAPIError
+	if !errors.As(err, &apiErr) {
+		return diag.Diagnostics{{Severity: diag.Error, Summary: summary, Detail: err.Error()}}
+	}
+
+	var model koyeb.ErrorWithFields
+	if jsonErr := json.Unmarshal(apiErr.Body(), &model); jsonErr != nil || model.GetMessage() == "" {
+		return diag.Diagnostics{{Severity: diag.Error, Summary: summary, Detail: apiErr.Error()}}
+	}
+
+	if len(model.Fields) == 0 {
+		detail := model.GetMessage()
+		if model.GetCode() != "" {
+			detail = fmt.Sprintf("%s (%s)", detail, model.GetCode())
+		}
+		return diag.Diagnostics{{Severity: diag.Error, Summary: summary, Detail: detail}}
+	}
+
+	diags := diag.Diagnostics{}
+	for _, field := range model.Fields {
+		path, name := apiFieldPath(field.GetField(), resourceSchema)
+		diags = append(diags, diag.Diagnostic{
+			Severity:      diag.Error,
+			Summary:       fmt.Sprintf("%s: %s", summary, model.GetMessage()),
+			Detail:        fmt.Sprintf("%s: %s", name, field.GetDescription()),
+			AttributePath: path,
+		})
+	}
+	return diags
+}
+
+// apiFieldPath returns the path of the attribute matching a field of the Koyeb
+// API, e.g. "definition.instance_types.0.type", along with its name in the
+// configuration, e.g. "definition.0.instance_types.0.type".
+//
+// Blocks limited to a single item are not indexed by the API. The returned
+// path stops at the first set, since set elements can only be addressed by
+// their value, and at the first field missing from the schema.
+func apiFieldPath(field string, resourceSchema map[string]*schema.Schema) (cty.Path, string) {
+	segments := strings.Split(field, ".")
+	if alias, ok := apiFieldAliases[segments[0]]; ok && resourceSchema[alias] != nil {
+		if attr := resourceSchema[segments[0]]; attr == nil || (!attr.Optional && !attr.Required) {
+			segments[0] = alias
+		}
+	}
+
+	path := cty.Path{}
+	names := []string{}
+	complete := true
+	current := resourceSchema
+
+	for i := 0; i < len(segments); i++ {
+		attr, ok := current[segments[i]]
+		if !ok {
+			names = append(names, segments[i:]...)
+			complete = false
+			break
+		}
+
+		names = append(names, segments[i])
+		if complete {
+			path = path.GetAttr(segments[i])
+		}
+
+		switch attr.Type {
+		case schema.TypeList, schema.TypeSet:
+			index := 0
+			if i+1 < len(segments) {
+				if n, err := strconv.Atoi(segments[i+1]); err == nil {
+					index = n
+					i++
+				} else if attr.MaxItems != 1 {
+					names = append(names, segments[i+1:]...)
+					return path, strings.Join(names, ".")
+				}
+			} else {
+				return path, strings.Join(names, ".")
+			}
+
+			names = append(names, strconv.Itoa(index))
+			if attr.Type == schema.TypeSet {
+				complete = false
+			}
+			if complete {
+				path = path.IndexInt(index)
+			}
+
+			elem, ok := attr.Elem.(*schema.Resource)
+			if !ok {
+				names = append(names, segments[i+1:]...)
+				return path, strings.Join(names, ".")
+			}
+			current = elem.Schema
+		case schema.TypeMap:
+			if i+1 < len(segments) && complete {
+				path = path.Index(cty.StringVal(strings.Join(segments[i+1:], ".")))
+			}
+			names = append(names, segments[i+1:]...)
+			return path, strings.Join(names, ".")
+		default:
+			names = append(names, segments[i+1:]...)
+			return path, strings.Join(names, ".")
+		}
+	}
+
+	return path, strings.Join(names, ".")
+}
@@ -0,0 +1,121 @@
+package koyeb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+func TestApiFieldPath(t *testing.T) {
+	serviceSchema := resourceKoyebService().Schema
+
+	tests := []struct {
+		field string
+		path  cty.Path
+		name  string
+	}{
+		{
+			field: "definition.instance_types.0.type",
+			path:  cty.GetAttrPath("definition").IndexInt(0).GetAttr("instance_types"),
+			name:  "definition.0.instance_types.0.type",
+		},
+		{
+			field: "definition.name",
+			path:  cty.GetAttrPath("definition").IndexInt(0).GetAttr("name"),
+			name:  "definition.0.name",
+		},
+		{
+			field: "app_id",
+			path:  cty.GetAttrPath("app_name"),
+			name:  "app_name",
+		},
+		{
+			field: "unknown.field",
+			path:  cty.Path{},
+			name:  "unknown.field",
+		},
+	}
+
+	for _, test := range tests {
+		path, name := apiFieldPath(test.field, serviceSchema)
+		if !path.Equals(test.path) {
+			t.Errorf("%s: expected path %#v, got %#v", test.field, test.path, path)
+		}
+		if name != test.name {
+			t.Errorf("%s: expected name %q, got %q", test.field, test.name, name)
+		}
+	}
+}
+
+func TestApiErrorDiagnostics_Fields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":400,"code":"invalid_argument","message":"Validation error","fields":[{"field":"definition.instance_types.0.type","description":"unknown instance type"}]}`))
+	}))
+	defer server.Close()
+
+	_, _, err := newTestClient(server.URL).ServicesApi.CreateService(context.Background()).Service(koyeb.CreateService{}).Execute()
+	diags := apiErrorDiagnostics("Error creating service", err, resourceKoyebService().Schema)
+
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(diags))
+	}
+	if diags[0].Summary != "Error creating service: Validation error" {
+		t.Errorf("unexpected summary %q", diags[0].Summary)
+	}
+	if diags[0].Detail != "definition.0.instance_types.0.type: unknown instance type" {
+		t.Errorf("unexpected detail %q", diags[0].Detail)
+	}
+	if len(diags[0].AttributePath) != 3 {
+		t.Errorf("expected the diagnostic to point to instance_types, got %#v", diags[0].AttributePath)
+	}
+}
+
+func TestApiErrorDiagnostics_Message(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"status":403,"code":"forbidden","message":"Quota exceeded"}`))
+	}))
+	defer server.Close()
+
+	_, _, err := newTestClient(server.URL).AppsApi.CreateApp(context.Background()).App(koyeb.CreateApp{}).Execute()
+	diags := apiErrorDiagnostics("Error creating app", err, resourceKoyebApp().Schema)
+
+	if len(diags) != 1 || diags[0].Detail != "Quota exceeded (forbidden)" || diags[0].AttributePath != nil {
+		t.Fatalf("unexpected diagnostics %#v", diags)
+	}
+}
+
+func TestResourceRead_NoResponse(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	client := newTestClient(server.URL)
+	server.Close()
+
+	resources := map[string]*schema.Resource{
+		"koyeb_app":     resourceKoyebApp(),
+		"koyeb_domain":  resourceKoyebDomain(),
+		"koyeb_secret":  resourceKoyebSecret(),
+		"koyeb_service": resourceKoyebService(),
+		"koyeb_volume":  resourceKoyebVolume(),
+	}
+
+	for name, resource := range resources {
+		d := resource.TestResourceData()
+		d.SetId("00000000-0000-4000-8000-000000000000")
+
+		diags := resource.ReadContext(context.Background(), d, newProviderMeta(client))
+		if !diags.HasError() {
+			t.Errorf("%s: expected an error when the API is unreachable", name)
+		}
+		if d.Id() == "" {
+			t.Errorf("%s: expected the resource not to be removed from the state", name)
+		}
+	}
+}
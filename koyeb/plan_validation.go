@@ -0,0 +1,125 @@
+package koyeb
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+// validateServiceWithAPI sends the planned service definition to the create or
+// update service endpoint in dry-run mode, so that the errors only detected by
+// the API fail the plan rather than the apply.
+//
+// The validation is skipped, with a debug log, as long as some values of the
+// definition are unknown, for archive sources which are only uploaded on
+// apply, for new services of apps which don't exist yet, like apps created by
+// the same apply, and for definitions with lists nested in sets, like the
+// docker args, which a ResourceDiff reads as lists of nil values when they
+// change.
+//
+// The returned error lists the rejected fields one per line, with the name of
+// the matching attribute, e.g. "definition.0.instance_types.0.type: <error>".
+func validateServiceWithAPI(ctx context.Context, d *schema.ResourceDiff, meta *providerMeta) error {
+	if d.Id() != "" && !definitionChanged(d) {
+		return nil
+	}
+
+	name := d.Get("definition.0.name").(string)
+	if key := unknownDefinitionKey(d); key != "" {
+		log.Printf("[DEBUG] Skipping the validation of service %s: %s is unknown", name, key)
+		return nil
+	}
+	if d.Get("definition.0.archive").(*schema.Set).Len() > 0 {
+		log.Printf("[DEBUG] Skipping the validation of service %s: its archive is only uploaded on apply", name)
+		return nil
+	}
+
+	rawDefinition := d.Get("definition").([]interface{})[0].(map[string]interface{})
+	if hasNilElement(rawDefinition) {
+		log.Printf("[DEBUG] Skipping the validation of service %s: lists nested in sets can't be read from the plan", name)
+		return nil
+	}
+	definition := expandDeploymentDefinition(rawDefinition)
+
+	var err error
+	if d.Id() == "" {
+		appId, resolveErr := meta.resolver.ResolveID(ctx, appKind, d.Get("app_name").(string))
+		if resolveErr != nil {
+			// The app may be created by the same apply
+			log.Printf("[DEBUG] Skipping the validation of service %s: %s", name, resolveErr)
+			return nil
+		}
+
+		_, _, err = meta.client.ServicesApi.CreateService(ctx).DryRun(true).Service(koyeb.CreateService{
+			AppId:      toOpt(appId),
+			Definition: definition,
+		}).Execute()
+	} else {
+		_, _, err = meta.client.ServicesApi.UpdateService(ctx, d.Id()).DryRun(true).Service(koyeb.UpdateService{
+			Definition: definition,
+		}).Execute()
+	}
+	if err != nil {
+		// Field diagnostics share their summary and name the attribute in
+		// their detail
+		diags := apiErrorDiagnostics("Invalid service definition", err, resourceKoyebService().Schema)
+		lines := []string{diags[0].Summary}
+		for _, diag := range diags {
+			lines = append(lines, diag.Detail)
+		}
+		return errors.New(strings.Join(lines, "\n"))
+	}
+
+	return nil
+}
+
+// unknownDefinitionKey returns the first attribute of the planned service
+// whose value is unknown, or an empty string. The computed attributes of the
+// definition which aren't configured, like the health checks, are ignored.
+func unknownDefinitionKey(d *schema.ResourceDiff) string {
+	if !d.NewValueKnown("app_name") {
+		return "app_name"
+	}
+
+	definitionSchema := deploymentDefinitionSchena().Schema
+	keys := d.GetChangedKeysPrefix("definition")
+	sort.Strings(keys)
+	for _, key := range keys {
+		if d.NewValueKnown(key) {
+			continue
+		}
+		attribute := strings.TrimSuffix(strings.TrimPrefix(key, "definition.0."), ".#")
+		if s, ok := definitionSchema[attribute]; ok && s.Computed {
+			continue
+		}
+		return key
+	}
+	return ""
+}
+
+// hasNilElement returns whether a list read from a ResourceDiff, or a list
+// nested in value, has nil elements.
+func hasNilElement(value interface{}) bool {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, v := range value {
+			if hasNilElement(v) {
+				return true
+			}
+		}
+	case *schema.Set:
+		return hasNilElement(value.List())
+	case []interface{}:
+		for _, v := range value {
+			if v == nil || hasNilElement(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package koyeb
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+func TestAccKoyebService_ValidatePlans(t *testing.T) {
+	var service koyeb.Service
+	var deployment koyeb.Deployment
+	appName := randomTestName()
+	t.Setenv("KOYEB_VALIDATE_PLANS", "true")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				// The app is created by the same apply, so the validation is
+				// skipped
+				Config:             fmt.Sprintf(testAccCheckKoyebServiceConfig_instance_type, appName, appName, "huge"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_app, appName),
+			},
+			{
+				Config:      fmt.Sprintf(testAccCheckKoyebServiceConfig_instance_type, appName, appName, "huge"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`Invalid service definition.*\ndefinition.0.instance_types.0.type: `),
+			},
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_instance_type, appName, appName, "nano"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					testAccCheckKoyebServiceLatestDeployment(&service, &deployment),
+				),
+			},
+			{
+				Config:      fmt.Sprintf(testAccCheckKoyebServiceConfig_instance_type, appName, appName, "huge"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`definition.0.instance_types.0.type: `),
+			},
+			{
+				Config:             fmt.Sprintf(testAccCheckKoyebServiceConfig_instance_type, appName, appName, "small"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				// The dry-run requests neither create nor update the service
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_instance_type, appName, appName, "nano"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					func(*terraform.State) error {
+						if service.GetLatestDeploymentId() != deployment.GetId() {
+							return fmt.Errorf("Service deployed by a dry-run: %s", service.GetLatestDeploymentId())
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccKoyebService_ValidatePlansDisabled(t *testing.T) {
+	var service koyeb.Service
+	appName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_instance_type, appName, appName, "nano"),
+				Check:  testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+			},
+			{
+				Config:             fmt.Sprintf(testAccCheckKoyebServiceConfig_instance_type, appName, appName, "huge"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestHasNilElement(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		value interface{}
+		want  bool
+	}{
+		{name: "empty", value: map[string]interface{}{"args": []interface{}{}}},
+		{name: "list", value: map[string]interface{}{"args": []interface{}{"--port", "8000"}}},
+		{name: "nil list element", value: map[string]interface{}{"args": []interface{}{nil, nil}}, want: true},
+		{
+			name: "nil element in a set",
+			value: map[string]interface{}{
+				"docker": schema.NewSet(func(interface{}) int { return 0 }, []interface{}{
+					map[string]interface{}{"image": "koyeb/demo", "args": []interface{}{nil}},
+				}),
+			},
+			want: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := hasNilElement(test.value); got != test.want {
+				t.Fatalf("expected %t, got %t", test.want, got)
+			}
+		})
+	}
+}
+
+const testAccCheckKoyebServiceConfig_app = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}`
+
+const testAccCheckKoyebServiceConfig_instance_type = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
+
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	definition {
+		name = "service"
+		instance_types {
+		  type = "%s"
+		}
+		scalings {
+		  min = 1
+		  max = 1
+		}
+		regions = ["fra"]
+		docker {
+		  image = "koyeb/demo"
+		}
+	}
+
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
@@ -3,13 +3,19 @@ package koyeb
 import (
 	"context"
 	"fmt"
-	"os"
+	_nethttp "net/http"
+	"net/url"
+	"strings"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
 )
 
+const defaultAPIURL = "https://app.koyeb.com"
+
 func init() {
 	// Set descriptions to support markdown syntax, this will be used in document generation
 	// and the language server.
@@ -26,9 +32,55 @@ func init() {
 	// }
 }
 
+func providerSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"token": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			DefaultFunc: schema.EnvDefaultFunc("KOYEB_TOKEN", nil),
+			Description: "The Koyeb API token. Can also be set with the `KOYEB_TOKEN` environment variable.",
+		},
+		"api_url": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			DefaultFunc:  schema.EnvDefaultFunc("KOYEB_API_URL", defaultAPIURL),
+			Description:  "The URL of the Koyeb API. Can also be set with the `KOYEB_API_URL` environment variable, defaults to `" + defaultAPIURL + "`.",
+			ValidateFunc: validation.IsURLWithHTTPorHTTPS,
+		},
+		"organization_id": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			DefaultFunc:  schema.EnvDefaultFunc("KOYEB_ORGANIZATION_ID", nil),
+			Description:  "The ID of the organization to manage resources in. When set, the token is exchanged for a token scoped to this organization. Can also be set with the `KOYEB_ORGANIZATION_ID` environment variable.",
+			ValidateFunc: validation.IsUUID,
+		},
+		"debug": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			DefaultFunc: schema.EnvDefaultFunc("KOYEB_DEBUG", false),
+			Description: "If set to true, the requests sent to and the responses received from the Koyeb API are logged. Can also be set with the `KOYEB_DEBUG` environment variable.",
+		},
+		"max_retries": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			DefaultFunc:  schema.EnvDefaultFunc("KOYEB_MAX_RETRIES", defaultMaxRetries),
+			Description:  "The maximum number of times a request to the Koyeb API is retried when it is rate limited or fails with a transient error. Can also be set with the `KOYEB_MAX_RETRIES` environment variable, defaults to `5`.",
+			ValidateFunc: validation.IntAtLeast(0),
+		},
+		"validate_plans": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			DefaultFunc: schema.EnvDefaultFunc("KOYEB_VALIDATE_PLANS", false),
+			Description: "If set to true, the service definitions planned by `koyeb_service` are validated by the Koyeb API in dry-run mode, so that the errors only detected by the API, like an instance type unavailable in a region or an exceeded quota, are reported by the plan. The validation is skipped, with a debug log, for new services of apps which don't exist yet, like apps created by the same apply, for definitions with values unknown until the apply or with an archive source, and when docker args, entrypoints or volume scopes change. Can also be set with the `KOYEB_VALIDATE_PLANS` environment variable.",
+		},
+	}
+}
+
 func New(version string) func() *schema.Provider {
 	return func() *schema.Provider {
 		p := &schema.Provider{
+			Schema: providerSchema(),
 			DataSourcesMap: map[string]*schema.Resource{
 				"koyeb_app":     dataSourceKoyebApp(),
 				"koyeb_service": dataSourceKoyebService(),
@@ -36,11 +88,12 @@ func New(version string) func() *schema.Provider {
 				"koyeb_secret":  dataSourceKoyebSecret(),
 			},
 			ResourcesMap: map[string]*schema.Resource{
-				"koyeb_app":     resourceKoyebApp(),
-				"koyeb_service": resourceKoyebService(),
-				"koyeb_domain":  resourceKoyebDomain(),
-				"koyeb_secret":  resourceKoyebSecret(),
-				"koyeb_volume":  resourceKoyebVolume(),
+				"koyeb_app":      resourceKoyebApp(),
+				"koyeb_service":  resourceKoyebService(),
+				"koyeb_domain":   resourceKoyebDomain(),
+				"koyeb_secret":   resourceKoyebSecret(),
+				"koyeb_volume":   resourceKoyebVolume(),
+				"koyeb_database": resourceKoyebDatabase(),
 			},
 		}
 
@@ -50,20 +103,108 @@ func New(version string) func() *schema.Provider {
 	}
 }
 
+// providerMeta is the value shared by the resources and data sources of a
+// configured provider.
+type providerMeta struct {
+	client   *koyeb.APIClient
+	resolver *nameResolver
+	// validatePlans enables the dry-run validation of the planned services
+	validatePlans bool
+}
+
+func newProviderMeta(client *koyeb.APIClient) *providerMeta {
+	return &providerMeta{
+		client:   client,
+		resolver: newNameResolver(client),
+	}
+}
+
+// clientConfig holds the settings used to build a Koyeb API client.
+type clientConfig struct {
+	Token          string
+	APIURL         string
+	OrganizationID string
+	Debug          bool
+	UserAgent      string
+	MaxRetries     int
+}
+
+// newAPIClient builds a Koyeb API client from config. When an organization ID
+// is set, the token is exchanged for a token scoped to that organization.
+func newAPIClient(ctx context.Context, config clientConfig) (*koyeb.APIClient, error) {
+	apiURL, err := url.Parse(config.APIURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL %q: %w", config.APIURL, err)
+	}
+
+	koyebClientConfig := koyeb.NewConfiguration()
+	koyebClientConfig.Servers[0].URL = strings.TrimSuffix(apiURL.String(), "/")
+	koyebClientConfig.Debug = config.Debug
+	koyebClientConfig.DefaultHeader["Authorization"] = fmt.Sprintf("Bearer %s", config.Token)
+	koyebClientConfig.UserAgent = config.UserAgent
+	koyebClientConfig.HTTPClient = &_nethttp.Client{
+		Transport: newRetryTransport(_nethttp.DefaultTransport, config.MaxRetries),
+	}
+
+	client := koyeb.NewAPIClient(koyebClientConfig)
+
+	if config.OrganizationID != "" {
+		// SwitchOrganization requires an empty body
+		body := make(map[string]interface{})
+		res, _, err := client.OrganizationApi.SwitchOrganization(ctx, config.OrganizationID).Body(body).Execute()
+		if err != nil {
+			return nil, fmt.Errorf("unable to switch to organization %s: %w", config.OrganizationID, err)
+		}
+
+		koyebClientConfig.DefaultHeader["Authorization"] = fmt.Sprintf("Bearer %s", res.Token.GetId())
+	}
+
+	return client, nil
+}
+
 func configure(p *schema.Provider, version string) func(context.Context, *schema.ResourceData) (interface{}, diag.Diagnostics) {
-	return func(context.Context, *schema.ResourceData) (interface{}, diag.Diagnostics) {
-		if os.Getenv("KOYEB_TOKEN") == "" {
-			return nil, diag.Errorf("Empty KOYEB_TOKEN environment variable")
+	return func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+		token := d.Get("token").(string)
+		if token == "" {
+			return nil, diag.Diagnostics{
+				{
+					Severity:      diag.Error,
+					Summary:       "Missing Koyeb API token",
+					Detail:        "The Koyeb API token must be set either with the `token` provider argument or with the KOYEB_TOKEN environment variable.",
+					AttributePath: cty.GetAttrPath("token"),
+				},
+			}
+		}
+
+		organizationID := d.Get("organization_id").(string)
+
+		client, err := newAPIClient(ctx, clientConfig{
+			Token:          token,
+			APIURL:         d.Get("api_url").(string),
+			OrganizationID: organizationID,
+			Debug:          d.Get("debug").(bool),
+			UserAgent:      p.UserAgent("terraform-provider-koyeb", version),
+			MaxRetries:     d.Get("max_retries").(int),
+		})
+		if err != nil {
+			path := cty.GetAttrPath("api_url")
+			if organizationID != "" {
+				path = cty.GetAttrPath("organization_id")
+			}
+
+			return nil, diag.Diagnostics{
+				{
+					Severity:      diag.Error,
+					Summary:       "Unable to configure the Koyeb API client",
+					Detail:        err.Error(),
+					AttributePath: path,
+				},
+			}
 		}
 
-		userAgent := p.UserAgent("terraform-provider-koyeb", version)
-		koyebClientConfig := koyeb.NewConfiguration()
-		koyebClientConfig.Host = "app.koyeb.com"
-		koyebClientConfig.Debug = os.Getenv("KOYEB_DEBUG") == "true"
-		koyebClientConfig.DefaultHeader["Authorization"] = fmt.Sprintf("Bearer %s", os.Getenv("KOYEB_TOKEN"))
-		koyebClientConfig.UserAgent = userAgent
+		meta := newProviderMeta(client)
+		meta.validatePlans = d.Get("validate_plans").(bool)
 
-		client := koyeb.NewAPIClient(koyebClientConfig)
-		return client, nil
+		return meta, nil
 	}
 }
@@ -3,13 +3,17 @@ package koyeb
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/koyeb/terraform-provider-koyeb/koyebtest"
 )
 
 const testNamePrefix = "tf-acc-test-"
@@ -52,3 +56,164 @@ func testAccPreCheck(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestProvider(t *testing.T) {
+	if err := New("test")().InternalValidate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestProvider_MissingToken(t *testing.T) {
+	t.Setenv("KOYEB_TOKEN", "")
+
+	p := New("test")()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(nil))
+	if !diags.HasError() {
+		t.Fatal("expected an error when no token is configured")
+	}
+	if diags[0].Summary != "Missing Koyeb API token" {
+		t.Fatalf("unexpected diagnostic: %s", diags[0].Summary)
+	}
+}
+
+func TestProvider_InvalidAPIURL(t *testing.T) {
+	p := New("test")()
+	diags := p.Validate(terraform.NewResourceConfigRaw(map[string]interface{}{
+		"token":   "token",
+		"api_url": "app.koyeb.com",
+	}))
+	if !diags.HasError() {
+		t.Fatal("expected an error for an API URL without scheme")
+	}
+}
+
+func TestProvider_OrganizationToken(t *testing.T) {
+	organizationID := "4fa4ac3f-7b1c-4d2e-9f5a-0c1a2b3c4d5e"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/organizations/"+organizationID+"/switch" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer user-token" {
+			t.Errorf("unexpected authorization header %q", r.Header.Get("Authorization"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"token": {"id": "organization-token"}}`)
+	}))
+	defer server.Close()
+
+	p := New("test")()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"token":           "user-token",
+		"api_url":         server.URL,
+		"organization_id": organizationID,
+	}))
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	client := p.Meta().(*providerMeta).client
+	if got := client.GetConfig().DefaultHeader["Authorization"]; got != "Bearer organization-token" {
+		t.Fatalf("expected the organization token to be used, got %q", got)
+	}
+}
+
+// testFakeAPIProvider returns a provider configured against a new fake Koyeb
+// API server.
+func testFakeAPIProvider(t *testing.T) (*schema.Provider, *koyebtest.Server) {
+	server := koyebtest.NewServer()
+	t.Cleanup(server.Close)
+
+	return testConfiguredProvider(t, server.URL, server.Token), server
+}
+
+func testConfiguredProvider(t *testing.T, apiURL string, token string) *schema.Provider {
+	p := New("test")()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"token":   token,
+		"api_url": apiURL,
+	}))
+	if diags.HasError() {
+		t.Fatalf("unable to configure the provider: %v", diags)
+	}
+
+	return p
+}
+
+func TestProvider_FakeAPI(t *testing.T) {
+	p, server := testFakeAPIProvider(t)
+	ctx := context.Background()
+
+	app := resourceKoyebApp()
+	appData := schema.TestResourceDataRaw(t, app.Schema, map[string]interface{}{
+		"name": "my-app",
+	})
+	if diags := app.CreateContext(ctx, appData, p.Meta()); diags.HasError() {
+		t.Fatalf("unable to create app: %v", diags)
+	}
+	if appData.Get("domains.0.name").(string) == "" {
+		t.Fatal("expected the app to have a domain")
+	}
+
+	service := resourceKoyebService()
+	serviceData := schema.TestResourceDataRaw(t, service.Schema, map[string]interface{}{
+		"app_name": "my-app",
+		"definition": []interface{}{
+			map[string]interface{}{
+				"name":    "main",
+				"regions": []interface{}{"fra"},
+				"docker": []interface{}{
+					map[string]interface{}{"image": "koyeb/demo"},
+				},
+				"instance_types": []interface{}{
+					map[string]interface{}{"type": "nano"},
+				},
+				"ports": []interface{}{
+					map[string]interface{}{"port": 3000, "protocol": "http"},
+				},
+			},
+		},
+	})
+	if diags := service.CreateContext(ctx, serviceData, p.Meta()); diags.HasError() {
+		t.Fatalf("unable to create service: %v", diags)
+	}
+
+	deployment, ok := server.Deployment(serviceData.Get("active_deployment").(string))
+	if !ok || deployment.GetStatus() != "HEALTHY" {
+		t.Fatalf("expected the service to have a healthy deployment, got %v", deployment.GetStatus())
+	}
+	if serviceData.Get("definition.0.health_checks.#").(int) != 1 {
+		t.Fatal("expected the default health check to be read")
+	}
+
+	if diags := service.DeleteContext(ctx, serviceData, p.Meta()); diags.HasError() {
+		t.Fatalf("unable to delete service: %v", diags)
+	}
+	if diags := app.DeleteContext(ctx, appData, p.Meta()); diags.HasError() {
+		t.Fatalf("unable to delete app: %v", diags)
+	}
+	if _, ok := server.App(appData.Id()); ok {
+		t.Fatal("expected the app to be deleted")
+	}
+}
+
+func TestProvider_Timeouts(t *testing.T) {
+	p := New("test")()
+
+	for name, r := range p.ResourcesMap {
+		if r.Timeouts == nil {
+			t.Errorf("expected %s to declare timeouts", name)
+			continue
+		}
+		for key, timeout := range map[string]*time.Duration{
+			schema.TimeoutCreate: r.Timeouts.Create,
+			schema.TimeoutUpdate: r.Timeouts.Update,
+			schema.TimeoutDelete: r.Timeouts.Delete,
+		} {
+			if timeout == nil {
+				t.Errorf("expected %s to declare a %s timeout", name, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package koyeb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+	"github.com/koyeb/koyeb-cli/pkg/koyeb/idmapper"
+)
+
+// resolverKind is a kind of object whose name can be resolved to an ID.
+type resolverKind string
+
+const (
+	appKind     resolverKind = "app"
+	domainKind  resolverKind = "domain"
+	secretKind  resolverKind = "secret"
+	serviceKind resolverKind = "service"
+	volumeKind  resolverKind = "volume"
+)
+
+// resolverPageSize is the number of objects fetched by each list request.
+const resolverPageSize = 100
+
+// resolverEntry is an object along with the names it can be referred to by.
+type resolverEntry struct {
+	ID    string
+	Names []string
+}
+
+// resolverCache holds the objects of a kind. keys is nil until the objects
+// are listed, and is reset when the cache is invalidated.
+type resolverCache struct {
+	mu      sync.Mutex
+	entries []resolverEntry
+	keys    map[string]string
+}
+
+// nameResolver resolves names to IDs. Unlike idmapper, it lives as long as
+// the provider and is shared by all resources and data sources, so each
+// collection is listed once rather than once per resource. It is safe for
+// concurrent use.
+type nameResolver struct {
+	client *koyeb.APIClient
+
+	mu     sync.Mutex
+	caches map[resolverKind]*resolverCache
+}
+
+func newNameResolver(client *koyeb.APIClient) *nameResolver {
+	return &nameResolver{
+		client: client,
+		caches: map[resolverKind]*resolverCache{},
+	}
+}
+
+func (r *nameResolver) cache(kind resolverKind) *resolverCache {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.caches[kind]
+	if !ok {
+		c = &resolverCache{}
+		r.caches[kind] = c
+	}
+	return c
+}
+
+// ResolveID returns the ID of the object of the given kind referred to by
+// value, which is either its ID, the first 8 characters of its ID, or its
+// name. Services are referred to by the name of their app and their name,
+// separated by a slash (e.g. my-app/my-service).
+//
+// UUIDs are returned as is. When value is not found in the cache, the
+// collection is listed again in case the object was created elsewhere.
+func (r *nameResolver) ResolveID(ctx context.Context, kind resolverKind, value string) (string, error) {
+	if idmapper.IsUUIDv4(value) {
+		return value, nil
+	}
+
+	c := r.cache(kind)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stale := c.keys != nil
+	if !stale {
+		if err := r.load(ctx, kind, c); err != nil {
+			return "", err
+		}
+	}
+
+	if id, ok := c.keys[value]; ok {
+		return id, nil
+	}
+
+	if stale {
+		if err := r.load(ctx, kind, c); err != nil {
+			return "", err
+		}
+		if id, ok := c.keys[value]; ok {
+			return id, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s %q not found", kind, value)
+}
+
+// Invalidate discards the cached objects of the given kinds. It is called
+// when objects are created or deleted.
+func (r *nameResolver) Invalidate(kinds ...resolverKind) {
+	for _, kind := range kinds {
+		c := r.cache(kind)
+		c.mu.Lock()
+		c.entries = nil
+		c.keys = nil
+		c.mu.Unlock()
+	}
+}
+
+// entries returns the objects of the given kind, listing them if they are not
+// cached.
+func (r *nameResolver) entries(ctx context.Context, kind resolverKind) ([]resolverEntry, error) {
+	c := r.cache(kind)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keys == nil {
+		if err := r.load(ctx, kind, c); err != nil {
+			return nil, err
+		}
+	}
+	return c.entries, nil
+}
+
+// load lists the objects of the given kind and fills c. The caller must hold
+// c.mu.
+func (r *nameResolver) load(ctx context.Context, kind resolverKind, c *resolverCache) error {
+	var entries []resolverEntry
+	var err error
+
+	switch kind {
+	case appKind:
+		entries, err = r.listApps(ctx)
+	case domainKind:
+		entries, err = r.listDomains(ctx)
+	case secretKind:
+		entries, err = r.listSecrets(ctx)
+	case serviceKind:
+		entries, err = r.listServices(ctx)
+	case volumeKind:
+		entries, err = r.listVolumes(ctx)
+	default:
+		return fmt.Errorf("unable to resolve objects of kind %s", kind)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to list objects of kind %s: %w", kind, err)
+	}
+
+	keys := map[string]string{}
+	shortIDs := map[string]int{}
+	for _, entry := range entries {
+		if len(entry.ID) >= 8 {
+			shortIDs[entry.ID[:8]]++
+		}
+	}
+	for _, entry := range entries {
+		keys[entry.ID] = entry.ID
+		// Short IDs are only used when they are not ambiguous
+		if len(entry.ID) >= 8 && shortIDs[entry.ID[:8]] == 1 {
+			keys[entry.ID[:8]] = entry.ID
+		}
+		for _, name := range entry.Names {
+			keys[name] = entry.ID
+		}
+	}
+
+	c.entries = entries
+	c.keys = keys
+	return nil
+}
+
+// listPages calls list with increasing offsets until all the objects are
+// listed. list returns the number of objects of the page and whether more
+// pages follow.
+func listPages(list func(limit string, offset int) (int, bool, error)) error {
+	for offset := 0; ; {
+		n, more, err := list(strconv.Itoa(resolverPageSize), offset)
+		if err != nil {
+			return err
+		}
+		offset += n
+		if n == 0 || !more {
+			return nil
+		}
+	}
+}
+
+func (r *nameResolver) listApps(ctx context.Context) ([]resolverEntry, error) {
+	entries := []resolverEntry{}
+	err := listPages(func(limit string, offset int) (int, bool, error) {
+		res, _, err := r.client.AppsApi.ListApps(ctx).Limit(limit).Offset(strconv.Itoa(offset)).Execute()
+		if err != nil {
+			return 0, false, err
+		}
+		for _, app := range res.GetApps() {
+			entries = append(entries, resolverEntry{ID: app.GetId(), Names: []string{app.GetName()}})
+		}
+		return len(res.GetApps()), int64(offset+len(res.GetApps())) < res.GetCount(), nil
+	})
+	return entries, err
+}
+
+func (r *nameResolver) listDomains(ctx context.Context) ([]resolverEntry, error) {
+	entries := []resolverEntry{}
+	err := listPages(func(limit string, offset int) (int, bool, error) {
+		res, _, err := r.client.DomainsApi.ListDomains(ctx).Limit(limit).Offset(strconv.Itoa(offset)).Execute()
+		if err != nil {
+			return 0, false, err
+		}
+		for _, domain := range res.GetDomains() {
+			entries = append(entries, resolverEntry{ID: domain.GetId(), Names: []string{domain.GetName()}})
+		}
+		return len(res.GetDomains()), int64(offset+len(res.GetDomains())) < res.GetCount(), nil
+	})
+	return entries, err
+}
+
+func (r *nameResolver) listSecrets(ctx context.Context) ([]resolverEntry, error) {
+	entries := []resolverEntry{}
+	err := listPages(func(limit string, offset int) (int, bool, error) {
+		res, _, err := r.client.SecretsApi.ListSecrets(ctx).Limit(limit).Offset(strconv.Itoa(offset)).Execute()
+		if err != nil {
+			return 0, false, err
+		}
+		for _, secret := range res.GetSecrets() {
+			entries = append(entries, resolverEntry{ID: secret.GetId(), Names: []string{secret.GetName()}})
+		}
+		return len(res.GetSecrets()), int64(offset+len(res.GetSecrets())) < res.GetCount(), nil
+	})
+	return entries, err
+}
+
+func (r *nameResolver) listServices(ctx context.Context) ([]resolverEntry, error) {
+	apps, err := r.entries(ctx, appKind)
+	if err != nil {
+		return nil, err
+	}
+	appNames := map[string]string{}
+	for _, app := range apps {
+		if len(app.Names) > 0 {
+			appNames[app.ID] = app.Names[0]
+		}
+	}
+
+	entries := []resolverEntry{}
+	err = listPages(func(limit string, offset int) (int, bool, error) {
+		res, _, err := r.client.ServicesApi.ListServices(ctx).Limit(limit).Offset(strconv.Itoa(offset)).Execute()
+		if err != nil {
+			return 0, false, err
+		}
+		for _, service := range res.GetServices() {
+			names := []string{fmt.Sprintf("%s/%s", service.GetAppId(), service.GetName())}
+			if appName, ok := appNames[service.GetAppId()]; ok {
+				names = append(names, fmt.Sprintf("%s/%s", appName, service.GetName()))
+			}
+			entries = append(entries, resolverEntry{ID: service.GetId(), Names: names})
+		}
+		return len(res.GetServices()), int64(offset+len(res.GetServices())) < res.GetCount(), nil
+	})
+	return entries, err
+}
+
+func (r *nameResolver) listVolumes(ctx context.Context) ([]resolverEntry, error) {
+	entries := []resolverEntry{}
+	err := listPages(func(limit string, offset int) (int, bool, error) {
+		res, _, err := r.client.PersistentVolumesApi.ListPersistentVolumes(ctx).Limit(limit).Offset(strconv.Itoa(offset)).Execute()
+		if err != nil {
+			return 0, false, err
+		}
+		for _, volume := range res.GetVolumes() {
+			entries = append(entries, resolverEntry{ID: volume.GetId(), Names: []string{volume.GetName()}})
+		}
+		return len(res.GetVolumes()), res.GetHasNext(), nil
+	})
+	return entries, err
+}
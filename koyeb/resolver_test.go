@@ -0,0 +1,156 @@
+package koyeb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+	"github.com/koyeb/terraform-provider-koyeb/koyebtest"
+)
+
+// countingTransport counts the list requests sent to the API.
+type countingTransport struct {
+	lists int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet && strings.Count(strings.Trim(req.URL.Path, "/"), "/") == 1 {
+		atomic.AddInt32(&t.lists, 1)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newResolverTestClient(t *testing.T) (*koyeb.APIClient, *countingTransport) {
+	server := koyebtest.NewServer()
+	t.Cleanup(server.Close)
+
+	transport := &countingTransport{}
+	config := koyeb.NewConfiguration()
+	config.Servers[0].URL = server.URL
+	config.DefaultHeader["Authorization"] = "Bearer " + server.Token
+	config.HTTPClient = &http.Client{Transport: transport}
+	return koyeb.NewAPIClient(config), transport
+}
+
+func createTestApp(t *testing.T, client *koyeb.APIClient, name string) string {
+	res, _, err := client.AppsApi.CreateApp(context.Background()).App(koyeb.CreateApp{Name: toOpt(name)}).Execute()
+	if err != nil {
+		t.Fatalf("unable to create app %s: %s", name, err)
+	}
+	return res.App.GetId()
+}
+
+func TestNameResolver_Cache(t *testing.T) {
+	client, transport := newResolverTestClient(t)
+	ctx := context.Background()
+
+	ids := map[string]string{}
+	for i := 0; i < 150; i++ {
+		name := fmt.Sprintf("app-%d", i)
+		ids[name] = createTestApp(t, client, name)
+	}
+
+	resolver := newNameResolver(client)
+	var wg sync.WaitGroup
+	for name, expected := range ids {
+		wg.Add(1)
+		go func(name string, expected string) {
+			defer wg.Done()
+			id, err := resolver.ResolveID(ctx, appKind, name)
+			if err != nil || id != expected {
+				t.Errorf("expected %s to resolve to %s, got %s %v", name, expected, id, err)
+			}
+		}(name, expected)
+	}
+	wg.Wait()
+
+	// 150 apps are listed in 2 pages, once for all the lookups
+	if lists := atomic.LoadInt32(&transport.lists); lists != 2 {
+		t.Fatalf("expected 2 list requests, got %d", lists)
+	}
+
+	id, err := resolver.ResolveID(ctx, appKind, ids["app-0"][:8])
+	if err != nil || id != ids["app-0"] {
+		t.Fatalf("expected the short ID to resolve, got %s %v", id, err)
+	}
+}
+
+func TestNameResolver_UUID(t *testing.T) {
+	client, transport := newResolverTestClient(t)
+
+	id := "8c3fd1c5-64a2-4b4f-a3c1-0e4d8a8b4f1e"
+	got, err := newNameResolver(client).ResolveID(context.Background(), serviceKind, id)
+	if err != nil || got != id {
+		t.Fatalf("expected the UUID to be returned as is, got %s %v", got, err)
+	}
+	if transport.lists != 0 {
+		t.Fatalf("expected no list request, got %d", transport.lists)
+	}
+}
+
+func TestNameResolver_Invalidate(t *testing.T) {
+	client, _ := newResolverTestClient(t)
+	ctx := context.Background()
+	resolver := newNameResolver(client)
+
+	first := createTestApp(t, client, "my-app")
+	if id, err := resolver.ResolveID(ctx, appKind, "my-app"); err != nil || id != first {
+		t.Fatalf("expected my-app to resolve to %s, got %s %v", first, id, err)
+	}
+
+	// Objects created after the collection was listed are found
+	other := createTestApp(t, client, "other-app")
+	if id, err := resolver.ResolveID(ctx, appKind, "other-app"); err != nil || id != other {
+		t.Fatalf("expected other-app to resolve to %s, got %s %v", other, id, err)
+	}
+
+	if _, _, err := client.AppsApi.DeleteApp(ctx, first).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, resp, _ := client.AppsApi.GetApp(ctx, first).Execute(); isNotFound(resp) {
+			break
+		}
+	}
+	resolver.Invalidate(appKind)
+
+	second := createTestApp(t, client, "my-app")
+	if id, err := resolver.ResolveID(ctx, appKind, "my-app"); err != nil || id != second {
+		t.Fatalf("expected my-app to resolve to %s after invalidation, got %s %v", second, id, err)
+	}
+
+	if _, err := resolver.ResolveID(ctx, appKind, "unknown"); err == nil {
+		t.Fatal("expected an error for an unknown app")
+	}
+}
+
+func TestNameResolver_Services(t *testing.T) {
+	client, _ := newResolverTestClient(t)
+	ctx := context.Background()
+
+	appID := createTestApp(t, client, "my-app")
+	res, _, err := client.ServicesApi.CreateService(ctx).Service(koyeb.CreateService{
+		AppId: toOpt(appID),
+		Definition: &koyeb.DeploymentDefinition{
+			Name:    toOpt("my-service"),
+			Regions: []string{"fra"},
+			Docker:  &koyeb.DockerSource{Image: toOpt("koyeb/demo")},
+		},
+	}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := newNameResolver(client)
+	for _, slug := range []string{"my-app/my-service", appID + "/my-service"} {
+		id, err := resolver.ResolveID(ctx, serviceKind, slug)
+		if err != nil || id != res.Service.GetId() {
+			t.Errorf("expected %s to resolve to %s, got %s %v", slug, res.Service.GetId(), id, err)
+		}
+	}
+}
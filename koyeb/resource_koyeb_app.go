@@ -2,13 +2,14 @@ package koyeb
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
-	"github.com/koyeb/koyeb-cli/pkg/koyeb/idmapper"
 )
 
 func appSchema() map[string]*schema.Schema {
@@ -30,6 +31,7 @@ func appSchema() map[string]*schema.Schema {
 			Computed:    true,
 			Description: "The organization ID owning the app",
 		},
+		"deletion_protection": deletionProtectionSchema("app"),
 		"domains": {
 			Type: schema.TypeList,
 			Elem: &schema.Resource{
@@ -59,12 +61,21 @@ func resourceKoyebApp() *schema.Resource {
 
 		CreateContext: resourceKoyebAppCreate,
 		ReadContext:   resourceKoyebAppRead,
+		UpdateContext: resourceKoyebAppUpdate,
 		DeleteContext: resourceKoyebAppDelete,
 
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importStateWithDeletionProtection,
 		},
 
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		CustomizeDiff: customizeDeletionProtectionDiff("app", appSchema()),
+
 		Schema: appSchema(),
 	}
 }
@@ -81,30 +92,42 @@ func setAppAttribute(d *schema.ResourceData, app koyeb.App) error {
 }
 
 func resourceKoyebAppCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	client := meta.(*providerMeta).client
 
-	res, resp, err := client.AppsApi.CreateApp(context.Background()).App(koyeb.CreateApp{
+	res, _, err := client.AppsApi.CreateApp(ctx).App(koyeb.CreateApp{
 		Name: toOpt(d.Get("name").(string)),
 	}).Execute()
 
 	if err != nil {
-		return diag.Errorf("Error creating app: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error creating app", err, resourceKoyebApp().Schema)
 	}
 
 	d.SetId(*res.App.Id)
+	meta.(*providerMeta).resolver.Invalidate(appKind)
 	log.Printf("[INFO] Created app name: %s", *res.App.Name)
 
+	err = (&statusWaiter{
+		Name:    fmt.Sprintf("app %s", d.Id()),
+		Refresh: appStatusFunc(client, d.Id()),
+		Pending: []string{string(koyeb.APPSTATUS_STARTING)},
+		Target:  []string{string(koyeb.APPSTATUS_HEALTHY)},
+		Failure: []string{string(koyeb.APPSTATUS_UNHEALTHY)},
+		Timeout: d.Timeout(schema.TimeoutCreate),
+	}).Wait(ctx)
+	if err != nil {
+		return diag.Errorf("Error waiting for app creation: %s", err)
+	}
+
 	return resourceKoyebAppRead(ctx, d, meta)
 }
 
 func resourceKoyebAppRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
-	mapper := idmapper.NewMapper(context.Background(), client)
-	appMapper := mapper.App()
+	client := meta.(*providerMeta).client
+	resolver := meta.(*providerMeta).resolver
 	var appId string
 
 	if d.Id() != "" {
-		id, err := appMapper.ResolveID(d.Id())
+		id, err := resolver.ResolveID(ctx, appKind, d.Id())
 
 		if err != nil {
 			return diag.Errorf("Error retrieving app: %s", err)
@@ -113,16 +136,16 @@ func resourceKoyebAppRead(ctx context.Context, d *schema.ResourceData, meta inte
 		appId = id
 	}
 
-	res, resp, err := client.AppsApi.GetApp(context.Background(), appId).Execute()
+	res, resp, err := client.AppsApi.GetApp(ctx, appId).Execute()
 	if err != nil {
 		// If the app is somehow already destroyed, mark as
 		// successfully gone
-		if resp.StatusCode == 404 {
+		if isNotFound(resp) {
 			d.SetId("")
 			return nil
 		}
 
-		return diag.Errorf("Error retrieving app: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error retrieving app", err, resourceKoyebApp().Schema)
 	}
 
 	setAppAttribute(d, *res.App)
@@ -130,13 +153,36 @@ func resourceKoyebAppRead(ctx context.Context, d *schema.ResourceData, meta inte
 	return nil
 }
 
+// resourceKoyebAppUpdate only updates the deletion protection, the other
+// attributes of the app force its replacement.
+func resourceKoyebAppUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	return resourceKoyebAppRead(ctx, d, meta)
+}
+
 func resourceKoyebAppDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	if diags := checkDeletionProtection(d, "app"); diags.HasError() {
+		return diags
+	}
+
+	client := meta.(*providerMeta).client
+
+	_, _, err := client.AppsApi.DeleteApp(ctx, d.Id()).Execute()
+
+	if err != nil {
+		return apiErrorDiagnostics("Error deleting app", err, resourceKoyebApp().Schema)
+	}
 
-	res, resp, err := client.AppsApi.DeleteApp(context.Background(), d.Id()).Execute()
+	meta.(*providerMeta).resolver.Invalidate(appKind, serviceKind, domainKind)
 
+	err = (&statusWaiter{
+		Name:             fmt.Sprintf("app %s", d.Id()),
+		Refresh:          appStatusFunc(client, d.Id()),
+		Target:           []string{string(koyeb.APPSTATUS_DELETED)},
+		NotFoundIsTarget: true,
+		Timeout:          d.Timeout(schema.TimeoutDelete),
+	}).Wait(ctx)
 	if err != nil {
-		return diag.Errorf("Error deleting app: %s (%v %v)", err, resp, res)
+		return diag.Errorf("Error waiting for app deletion: %s", err)
 	}
 
 	d.SetId("")
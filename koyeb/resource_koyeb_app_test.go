@@ -6,6 +6,7 @@ import (
 	"log"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
@@ -84,7 +85,7 @@ func TestAccKoyebApp_Basic(t *testing.T) {
 }
 
 func testAccCheckKoyebAppDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(*koyeb.APIClient)
+	client := testAccProvider.Meta().(*providerMeta).client
 	targetStatus := []string{"DELETED", "DELETING"}
 
 	for _, rs := range s.RootModule().Resources {
@@ -92,8 +93,14 @@ func testAccCheckKoyebAppDestroy(s *terraform.State) error {
 			continue
 		}
 
-		err := waitForResourceStatus(client.AppsApi.GetApp(context.Background(), rs.Primary.ID).Execute, "App", targetStatus, 1, false)
-		if err == nil {
+		err := (&statusWaiter{
+			Name:             fmt.Sprintf("app %s", rs.Primary.ID),
+			Refresh:          appStatusFunc(client, rs.Primary.ID),
+			Target:           targetStatus,
+			NotFoundIsTarget: true,
+			Timeout:          time.Minute,
+		}).Wait(context.Background())
+		if err != nil {
 			return fmt.Errorf("App still exists: %s ", err)
 		}
 	}
@@ -123,7 +130,7 @@ func testAccCheckKoyebAppExists(n string, app *koyeb.App) resource.TestCheckFunc
 			return fmt.Errorf("No Record ID is set")
 		}
 
-		client := testAccProvider.Meta().(*koyeb.APIClient)
+		client := testAccProvider.Meta().(*providerMeta).client
 
 		res, _, err := client.AppsApi.GetApp(context.Background(), rs.Primary.ID).Execute()
 
@@ -0,0 +1,477 @@
+package koyeb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+const (
+	defaultDatabaseName  = "koyebdb"
+	defaultDatabaseOwner = "koyeb-adm"
+)
+
+func databaseSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"id": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The database service ID",
+		},
+		"name": {
+			Type:         schema.TypeString,
+			Required:     true,
+			ForceNew:     true,
+			Description:  "The database service name",
+			ValidateFunc: validation.StringLenBetween(3, 64),
+		},
+		"app_name": {
+			Type:         schema.TypeString,
+			Required:     true,
+			ForceNew:     true,
+			Description:  "The app name the database service is assigned to",
+			ValidateFunc: validation.StringLenBetween(3, 23),
+		},
+		"app_id": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The app id the database service is assigned to",
+		},
+		"engine_version": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Default:      16,
+			ForceNew:     true,
+			Description:  "The PostgreSQL version (default 16)",
+			ValidateFunc: validation.IntBetween(14, 17),
+		},
+		"region": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Default:     "was",
+			ForceNew:    true,
+			Description: "The region where the database is deployed (default was)",
+		},
+		"instance_type": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      "free",
+			Description:  "The database instance type, one of free, small, medium or large (default free)",
+			ValidateFunc: validation.StringInSlice([]string{"free", "small", "medium", "large"}, false),
+		},
+		"roles": {
+			Type:        schema.TypeSet,
+			Optional:    true,
+			Computed:    true,
+			Description: "The roles to create, defaults to a `" + defaultDatabaseOwner + "` role",
+			Elem:        databaseRoleSchema(),
+			Set:         schema.HashResource(databaseRoleSchema()),
+		},
+		"databases": {
+			Type:        schema.TypeSet,
+			Optional:    true,
+			Computed:    true,
+			Description: "The databases to create, defaults to a `" + defaultDatabaseName + "` database owned by the first role",
+			Elem:        databaseDatabaseSchema(),
+			Set:         schema.HashResource(databaseDatabaseSchema()),
+		},
+		"host": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+			Description: "The host to connect to the database",
+		},
+		"port": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Sensitive:   true,
+			Description: "The port to connect to the database",
+		},
+		"user": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+			Description: "The user to connect to the database, which is the owner of the first database in alphabetical order",
+		},
+		"password": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+			Description: "The password of `user`",
+		},
+		"organization_id": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The organization ID owning the database service",
+		},
+		"active_deployment": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The database service active deployment ID",
+		},
+		"latest_deployment": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The database service latest deployment ID",
+		},
+		"status": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The status of the database service",
+		},
+		"updated_at": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The date and time of when the database service was last updated",
+		},
+		"created_at": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The date and time of when the database service was created",
+		},
+	}
+}
+
+func databaseRoleSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The role name",
+			},
+		},
+	}
+}
+
+func databaseDatabaseSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The database name",
+			},
+			"owner": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The role owning the database",
+			},
+		},
+	}
+}
+
+func resourceKoyebDatabase() *schema.Resource {
+	return &schema.Resource{
+		Description: "Database resource in the Koyeb Terraform provider.",
+
+		CreateContext: resourceKoyebDatabaseCreate,
+		ReadContext:   resourceKoyebDatabaseRead,
+		UpdateContext: resourceKoyebDatabaseUpdate,
+		DeleteContext: resourceKoyebDatabaseDelete,
+
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(15 * time.Minute),
+			Update: schema.DefaultTimeout(15 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+
+		Schema: databaseSchema(),
+	}
+}
+
+// expandDatabaseDefinition returns the deployment definition of the database
+// service. The password of each role is stored by Koyeb in a secret, whose
+// name is taken from roleSecrets for existing roles and generated for new
+// ones.
+func expandDatabaseDefinition(d *schema.ResourceData, roleSecrets map[string]string) *koyeb.DeploymentDefinition {
+	neon := &koyeb.NeonPostgresDatabase{
+		PgVersion:    toOpt(int64(d.Get("engine_version").(int))),
+		Region:       toOpt(d.Get("region").(string)),
+		InstanceType: toOpt(d.Get("instance_type").(string)),
+		Roles:        []koyeb.NeonPostgresDatabaseNeonRole{},
+		Databases:    []koyeb.NeonPostgresDatabaseNeonDatabase{},
+	}
+
+	roles := []string{}
+	for _, rawRole := range d.Get("roles").(*schema.Set).List() {
+		roles = append(roles, rawRole.(map[string]interface{})["name"].(string))
+	}
+	if len(roles) == 0 {
+		roles = append(roles, defaultDatabaseOwner)
+	}
+	sort.Strings(roles)
+
+	for _, role := range roles {
+		secret, ok := roleSecrets[role]
+		if !ok {
+			secret = fmt.Sprintf("%s-%s", d.Get("name").(string), uuid.NewString()[:8])
+		}
+		neon.Roles = append(neon.Roles, koyeb.NeonPostgresDatabaseNeonRole{
+			Name:   toOpt(role),
+			Secret: toOpt(secret),
+		})
+	}
+
+	for _, rawDatabase := range d.Get("databases").(*schema.Set).List() {
+		database := rawDatabase.(map[string]interface{})
+		neon.Databases = append(neon.Databases, koyeb.NeonPostgresDatabaseNeonDatabase{
+			Name:  toOpt(database["name"].(string)),
+			Owner: toOpt(database["owner"].(string)),
+		})
+	}
+	if len(neon.Databases) == 0 {
+		neon.Databases = append(neon.Databases, koyeb.NeonPostgresDatabaseNeonDatabase{
+			Name:  toOpt(defaultDatabaseName),
+			Owner: toOpt(roles[0]),
+		})
+	}
+	sort.Slice(neon.Databases, func(i, j int) bool {
+		return neon.Databases[i].GetName() < neon.Databases[j].GetName()
+	})
+
+	return &koyeb.DeploymentDefinition{
+		Name:     toOpt(d.Get("name").(string)),
+		Type:     koyeb.DEPLOYMENTDEFINITIONTYPE_DATABASE.Ptr(),
+		Database: &koyeb.DatabaseSource{NeonPostgres: neon},
+	}
+}
+
+func flattenDatabaseRoles(roles []koyeb.NeonPostgresDatabaseNeonRole) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(roles))
+
+	for i, role := range roles {
+		result[i] = map[string]interface{}{
+			"name": role.GetName(),
+		}
+	}
+
+	return result
+}
+
+func flattenDatabaseDatabases(databases []koyeb.NeonPostgresDatabaseNeonDatabase) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(databases))
+
+	for i, database := range databases {
+		result[i] = map[string]interface{}{
+			"name":  database.GetName(),
+			"owner": database.GetOwner(),
+		}
+	}
+
+	return result
+}
+
+// databaseUser returns the owner of the first database in alphabetical order.
+func databaseUser(databases []koyeb.NeonPostgresDatabaseNeonDatabase) string {
+	var user, first string
+	for _, database := range databases {
+		if first == "" || database.GetName() < first {
+			first = database.GetName()
+			user = database.GetOwner()
+		}
+	}
+	return user
+}
+
+func setDatabaseAttribute(
+	d *schema.ResourceData,
+	service *koyeb.Service,
+	latestDeployment *koyeb.Deployment,
+) error {
+	neon := latestDeployment.GetDefinition().Database.GetNeonPostgres()
+	info := latestDeployment.GetDatabaseInfo().NeonPostgres
+
+	d.SetId(service.GetId())
+	d.Set("name", service.GetName())
+	d.Set("app_id", service.GetAppId())
+	d.Set("engine_version", int(neon.GetPgVersion()))
+	d.Set("region", neon.GetRegion())
+	d.Set("instance_type", neon.GetInstanceType())
+	d.Set("roles", flattenDatabaseRoles(neon.Roles))
+	d.Set("databases", flattenDatabaseDatabases(neon.Databases))
+	d.Set("host", info.GetServerHost())
+	d.Set("port", int(info.GetServerPort()))
+	d.Set("organization_id", service.GetOrganizationId())
+	d.Set("active_deployment", service.GetActiveDeploymentId())
+	d.Set("latest_deployment", service.GetLatestDeploymentId())
+	d.Set("status", service.GetStatus())
+	d.Set("updated_at", service.GetUpdatedAt().UTC().String())
+	d.Set("created_at", service.GetCreatedAt().UTC().String())
+
+	return nil
+}
+
+// readDatabaseCredentials reveals the password of the database user, which
+// Koyeb stores in a secret once the role is provisioned.
+func readDatabaseCredentials(ctx context.Context, client *koyeb.APIClient, d *schema.ResourceData, deployment *koyeb.Deployment) diag.Diagnostics {
+	user := databaseUser(deployment.GetDefinition().Database.GetNeonPostgres().Databases)
+
+	var secretId string
+	for _, role := range deployment.GetDatabaseInfo().NeonPostgres.GetRoles() {
+		if role.GetName() == user {
+			secretId = role.GetSecretId()
+		}
+	}
+	if secretId == "" {
+		d.Set("user", "")
+		d.Set("password", "")
+		return nil
+	}
+
+	// RevealSecret requires an empty body
+	body := make(map[string]interface{})
+	res, _, err := client.SecretsApi.RevealSecret(ctx, secretId).Body(body).Execute()
+	if err != nil {
+		return apiErrorDiagnostics("Error revealing database password", err, resourceKoyebDatabase().Schema)
+	}
+
+	username, _ := res.Value["username"].(string)
+	password, _ := res.Value["password"].(string)
+	if username == "" {
+		username = user
+	}
+	d.Set("user", username)
+	d.Set("password", password)
+
+	return nil
+}
+
+func resourceKoyebDatabaseCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*providerMeta).client
+	resolver := meta.(*providerMeta).resolver
+
+	appId, err := resolver.ResolveID(ctx, appKind, d.Get("app_name").(string))
+	if err != nil {
+		return diag.Errorf("Error creating database: %s", err)
+	}
+
+	res, _, err := client.ServicesApi.CreateService(ctx).Service(koyeb.CreateService{
+		AppId:      toOpt(appId),
+		Definition: expandDatabaseDefinition(d, nil),
+	}).Execute()
+	if err != nil {
+		return apiErrorDiagnostics("Error creating database", err, resourceKoyebDatabase().Schema)
+	}
+
+	d.SetId(res.Service.GetId())
+	resolver.Invalidate(serviceKind)
+	log.Printf("[INFO] Created database name: %s", res.Service.GetName())
+
+	err = waitForDeployment(ctx, client, res.Service.GetLatestDeploymentId(), d.Timeout(schema.TimeoutCreate))
+	if err != nil {
+		return diag.Errorf("Error waiting for database deployment: %s", err)
+	}
+
+	return resourceKoyebDatabaseRead(ctx, d, meta)
+}
+
+func resourceKoyebDatabaseRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*providerMeta).client
+	resolver := meta.(*providerMeta).resolver
+	var serviceId string
+
+	if d.Id() != "" {
+		id, err := resolver.ResolveID(ctx, serviceKind, d.Id())
+		if err != nil {
+			return diag.Errorf("Error retrieving database: %s", err)
+		}
+
+		serviceId = id
+	}
+
+	serviceRes, resp, err := client.ServicesApi.GetService(ctx, serviceId).Execute()
+	if err != nil {
+		// If the database is somehow already destroyed, mark as
+		// successfully gone
+		if isNotFound(resp) {
+			d.SetId("")
+			return nil
+		}
+
+		return apiErrorDiagnostics("Error retrieving database", err, resourceKoyebDatabase().Schema)
+	}
+
+	if serviceRes.Service.GetType() != koyeb.SERVICETYPE_DATABASE {
+		return diag.Errorf("Error retrieving database: service %s is not a database", serviceRes.Service.GetName())
+	}
+
+	deploymentRes, _, err := client.DeploymentsApi.GetDeployment(ctx, serviceRes.Service.GetLatestDeploymentId()).Execute()
+	if err != nil {
+		return apiErrorDiagnostics("Error retrieving database latest deployment", err, resourceKoyebDatabase().Schema)
+	}
+
+	setDatabaseAttribute(d, serviceRes.Service, deploymentRes.Deployment)
+
+	return readDatabaseCredentials(ctx, client, d, deploymentRes.Deployment)
+}
+
+func resourceKoyebDatabaseUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*providerMeta).client
+
+	if !d.HasChanges("instance_type", "roles", "databases") {
+		return resourceKoyebDatabaseRead(ctx, d, meta)
+	}
+
+	// The roles which already exist keep the secret holding their password
+	deploymentRes, _, err := client.DeploymentsApi.GetDeployment(ctx, d.Get("latest_deployment").(string)).Execute()
+	if err != nil {
+		return apiErrorDiagnostics("Error retrieving database latest deployment", err, resourceKoyebDatabase().Schema)
+	}
+	roleSecrets := map[string]string{}
+	for _, role := range deploymentRes.Deployment.GetDefinition().Database.GetNeonPostgres().Roles {
+		roleSecrets[role.GetName()] = role.GetSecret()
+	}
+
+	res, _, err := client.ServicesApi.UpdateService(ctx, d.Id()).Service(koyeb.UpdateService{
+		Definition: expandDatabaseDefinition(d, roleSecrets),
+	}).Execute()
+	if err != nil {
+		return apiErrorDiagnostics("Error updating database", err, resourceKoyebDatabase().Schema)
+	}
+	log.Printf("[INFO] Updated database name: %s", res.Service.GetName())
+
+	err = waitForDeployment(ctx, client, res.Service.GetLatestDeploymentId(), d.Timeout(schema.TimeoutUpdate))
+	if err != nil {
+		return diag.Errorf("Error waiting for database deployment: %s", err)
+	}
+
+	return resourceKoyebDatabaseRead(ctx, d, meta)
+}
+
+func resourceKoyebDatabaseDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*providerMeta).client
+
+	_, _, err := client.ServicesApi.DeleteService(ctx, d.Id()).Execute()
+	if err != nil {
+		return apiErrorDiagnostics("Error deleting database", err, resourceKoyebDatabase().Schema)
+	}
+
+	meta.(*providerMeta).resolver.Invalidate(serviceKind)
+
+	err = (&statusWaiter{
+		Name:             fmt.Sprintf("database %s", d.Id()),
+		Refresh:          serviceStatusFunc(client, d.Id()),
+		Target:           []string{string(koyeb.SERVICESTATUS_DELETED)},
+		NotFoundIsTarget: true,
+		Timeout:          d.Timeout(schema.TimeoutDelete),
+	}).Wait(ctx)
+	if err != nil {
+		return diag.Errorf("Error waiting for database deletion: %s", err)
+	}
+
+	d.SetId("")
+	return nil
+}
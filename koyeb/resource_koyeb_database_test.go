@@ -0,0 +1,146 @@
+package koyeb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+func TestAccKoyebDatabase_Basic(t *testing.T) {
+	appName := randomTestName()
+	databaseName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebDatabaseDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebDatabaseConfig_basic, appName, databaseName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("koyeb_database.foobar", "name", databaseName),
+					resource.TestCheckResourceAttr("koyeb_database.foobar", "engine_version", "16"),
+					resource.TestCheckResourceAttr("koyeb_database.foobar", "user", defaultDatabaseOwner),
+					resource.TestCheckResourceAttr("koyeb_database.foobar", "databases.#", "1"),
+					resource.TestCheckResourceAttrSet("koyeb_database.foobar", "id"),
+					resource.TestCheckResourceAttrSet("koyeb_database.foobar", "app_id"),
+					resource.TestCheckResourceAttrSet("koyeb_database.foobar", "host"),
+					resource.TestCheckResourceAttrSet("koyeb_database.foobar", "port"),
+					resource.TestCheckResourceAttrSet("koyeb_database.foobar", "password"),
+					resource.TestCheckResourceAttrSet("koyeb_database.foobar", "status"),
+					resource.TestCheckResourceAttrSet("koyeb_database.foobar", "created_at"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccKoyebDatabase_Roles(t *testing.T) {
+	var database koyeb.Service
+	var deployment koyeb.Deployment
+	var password string
+	appName := randomTestName()
+	databaseName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebDatabaseDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebDatabaseConfig_roles, appName, databaseName, "free"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("koyeb_database.foobar", "port", "5432"),
+					resource.TestCheckResourceAttrSet("koyeb_database.foobar", "host"),
+					// analytics is the first database, owned by reader
+					resource.TestCheckResourceAttr("koyeb_database.foobar", "user", "reader"),
+					resource.TestCheckResourceAttrSet("koyeb_database.foobar", "password"),
+					func(s *terraform.State) error {
+						password = s.RootModule().Resources["koyeb_database.foobar"].Primary.Attributes["password"]
+						return nil
+					},
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebDatabaseConfig_roles, appName, databaseName, "small"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_database.foobar", &database),
+					testAccCheckKoyebServiceLatestDeployment(&database, &deployment),
+					func(*terraform.State) error {
+						if instanceType := deployment.Definition.Database.NeonPostgres.GetInstanceType(); instanceType != "small" {
+							return fmt.Errorf("Database not resized, got %s", instanceType)
+						}
+						return nil
+					},
+					// The password is kept across updates
+					resource.TestCheckResourceAttrPtr("koyeb_database.foobar", "password", &password),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckKoyebDatabaseDestroy(s *terraform.State) error {
+	client := testAccProvider.Meta().(*providerMeta).client
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "koyeb_database" {
+			continue
+		}
+
+		err := (&statusWaiter{
+			Name:             fmt.Sprintf("database %s", rs.Primary.ID),
+			Refresh:          serviceStatusFunc(client, rs.Primary.ID),
+			Target:           []string{string(koyeb.SERVICESTATUS_DELETED)},
+			NotFoundIsTarget: true,
+			Timeout:          time.Minute,
+		}).Wait(context.Background())
+		if err != nil {
+			return fmt.Errorf("Database still exists: %s ", err)
+		}
+	}
+
+	return nil
+}
+
+const testAccCheckKoyebDatabaseConfig_basic = `
+resource "koyeb_app" "foobar" {
+	name = "%s"
+}
+
+resource "koyeb_database" "foobar" {
+	name     = "%s"
+	app_name = koyeb_app.foobar.name
+}`
+
+const testAccCheckKoyebDatabaseConfig_roles = `
+resource "koyeb_app" "foobar" {
+	name = "%s"
+}
+
+resource "koyeb_database" "foobar" {
+	name          = "%s"
+	app_name      = koyeb_app.foobar.name
+	instance_type = "%s"
+
+	roles {
+		name = "admin"
+	}
+	roles {
+		name = "reader"
+	}
+
+	databases {
+		name  = "orders"
+		owner = "admin"
+	}
+	databases {
+		name  = "analytics"
+		owner = "reader"
+	}
+}`
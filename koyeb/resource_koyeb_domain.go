@@ -2,14 +2,15 @@ package koyeb
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
-	"github.com/koyeb/koyeb-cli/pkg/koyeb/idmapper"
 )
 
 func domainSchema() map[string]*schema.Schema {
@@ -116,7 +117,7 @@ func flattenDomains(domains *[]koyeb.Domain, appName string) []map[string]interf
 		}
 
 		if verifiedAt, ok := domain.GetVerifiedAtOk(); ok {
-			r["verified_at"] = verifiedAt
+			r["verified_at"] = verifiedAt.UTC().String()
 		}
 
 		r["updated_at"] = domain.GetUpdatedAt().UTC().String()
@@ -137,6 +138,12 @@ func resourceKoyebDomain() *schema.Resource {
 		UpdateContext: resourceKoyebDomainUpdate,
 		DeleteContext: resourceKoyebDomainDelete,
 
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
 		Schema: domainSchema(),
 	}
 }
@@ -163,13 +170,12 @@ func setDomainAttribute(
 }
 
 func resourceKoyebDomainCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
-	mapper := idmapper.NewMapper(context.Background(), client)
-	appMapper := mapper.App()
+	client := meta.(*providerMeta).client
+	resolver := meta.(*providerMeta).resolver
 	var appId string
 
 	if d.Get("app_name").(string) != "" {
-		id, err := appMapper.ResolveID(d.Get("app_name").(string))
+		id, err := resolver.ResolveID(ctx, appKind, d.Get("app_name").(string))
 
 		if err != nil {
 			return diag.Errorf("Error creating domain: %s", err)
@@ -178,41 +184,46 @@ func resourceKoyebDomainCreate(ctx context.Context, d *schema.ResourceData, meta
 		appId = id
 	}
 
-	res, resp, err := client.DomainsApi.CreateDomain(context.Background()).Domain(koyeb.CreateDomain{
+	res, _, err := client.DomainsApi.CreateDomain(ctx).Domain(koyeb.CreateDomain{
 		Name:  toOpt(d.Get("name").(string)),
 		AppId: &appId,
 		Type:  toOpt(koyeb.DOMAINTYPE_CUSTOM),
 	}).Execute()
 	if err != nil {
-		return diag.Errorf("Error creating domain: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error creating domain", err, resourceKoyebDomain().Schema)
 	}
 
 	d.SetId(*res.Domain.Id)
+	meta.(*providerMeta).resolver.Invalidate(domainKind)
 	log.Printf("[INFO] Created domain name: %s", *res.Domain.Name)
 
+	if err := waitForDomain(ctx, client, d.Id(), d.Timeout(schema.TimeoutCreate)); err != nil {
+		return diag.Errorf("Error waiting for domain creation: %s", err)
+	}
+
 	return resourceKoyebDomainRead(ctx, d, meta)
 }
 
 func resourceKoyebDomainRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	client := meta.(*providerMeta).client
 	appName := ""
 
-	res, resp, err := client.DomainsApi.GetDomain(context.Background(), d.Id()).Execute()
+	res, resp, err := client.DomainsApi.GetDomain(ctx, d.Id()).Execute()
 	if err != nil {
 		// If the domain is somehow already destroyed, mark as
 		// successfully gone
-		if resp.StatusCode == 404 {
+		if isNotFound(resp) {
 			d.SetId("")
 			return nil
 		}
 
-		return diag.Errorf("Error retrieving domain: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error retrieving domain", err, resourceKoyebDomain().Schema)
 	}
 
-	if *res.Domain.AppId != "" {
-		res, resp, err := client.AppsApi.GetApp(context.Background(), *res.Domain.AppId).Execute()
+	if res.Domain.GetAppId() != "" {
+		res, _, err := client.AppsApi.GetApp(ctx, res.Domain.GetAppId()).Execute()
 		if err != nil {
-			return diag.Errorf("Error retrieving app assigned to domain: %s (%v %v)", err, resp, res)
+			return apiErrorDiagnostics("Error retrieving app assigned to domain", err, resourceKoyebDomain().Schema)
 		}
 
 		appName = *res.App.Name
@@ -224,13 +235,12 @@ func resourceKoyebDomainRead(ctx context.Context, d *schema.ResourceData, meta i
 }
 
 func resourceKoyebDomainUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
-	mapper := idmapper.NewMapper(context.Background(), client)
-	appMapper := mapper.App()
+	client := meta.(*providerMeta).client
+	resolver := meta.(*providerMeta).resolver
 	var appId string
 
 	if d.Get("app_name").(string) != "" {
-		id, err := appMapper.ResolveID(d.Get("app_name").(string))
+		id, err := resolver.ResolveID(ctx, appKind, d.Get("app_name").(string))
 
 		if err != nil {
 			return diag.Errorf("Error creating domain: %s", err)
@@ -239,23 +249,40 @@ func resourceKoyebDomainUpdate(ctx context.Context, d *schema.ResourceData, meta
 		appId = id
 	}
 
-	res, resp, err := client.DomainsApi.UpdateDomain(context.Background(), d.Id()).Domain(koyeb.UpdateDomain{AppId: &appId}).Execute()
+	res, _, err := client.DomainsApi.UpdateDomain(ctx, d.Id()).Domain(koyeb.UpdateDomain{AppId: &appId}).Execute()
 
 	if err != nil {
-		return diag.Errorf("Error retrieving domain: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error updating domain", err, resourceKoyebDomain().Schema)
 	}
 
 	log.Printf("[INFO] Updated domain name: %s", *res.Domain.Name)
+
+	if err := waitForDomain(ctx, client, d.Id(), d.Timeout(schema.TimeoutUpdate)); err != nil {
+		return diag.Errorf("Error waiting for domain update: %s", err)
+	}
 	return resourceKoyebDomainRead(ctx, d, meta)
 }
 
 func resourceKoyebDomainDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	client := meta.(*providerMeta).client
+
+	_, _, err := client.DomainsApi.DeleteDomain(ctx, d.Id()).Execute()
+
+	if err != nil {
+		return apiErrorDiagnostics("Error deleting domain", err, resourceKoyebDomain().Schema)
+	}
 
-	res, resp, err := client.DomainsApi.DeleteDomain(context.Background(), d.Id()).Execute()
+	meta.(*providerMeta).resolver.Invalidate(domainKind)
 
+	err = (&statusWaiter{
+		Name:             fmt.Sprintf("domain %s", d.Id()),
+		Refresh:          domainStatusFunc(client, d.Id()),
+		Target:           []string{string(koyeb.DOMAINSTATUS_DELETED)},
+		NotFoundIsTarget: true,
+		Timeout:          d.Timeout(schema.TimeoutDelete),
+	}).Wait(ctx)
 	if err != nil {
-		return diag.Errorf("Error deleting domain: %s (%v %v)", err, resp, res)
+		return diag.Errorf("Error waiting for domain deletion: %s", err)
 	}
 
 	d.SetId("")
@@ -6,6 +6,7 @@ import (
 	"log"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
@@ -101,7 +102,7 @@ func TestAccKoyebDomain_Basic(t *testing.T) {
 }
 
 func testAccCheckKoyebDomainDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(*koyeb.APIClient)
+	client := testAccProvider.Meta().(*providerMeta).client
 	targetStatus := []string{"DELETED", "DELETING"}
 
 	for _, rs := range s.RootModule().Resources {
@@ -109,7 +110,13 @@ func testAccCheckKoyebDomainDestroy(s *terraform.State) error {
 			continue
 		}
 
-		err := waitForResourceStatus(client.DomainsApi.GetDomain(context.Background(), rs.Primary.ID).Execute, "Domain", targetStatus, 1, false)
+		err := (&statusWaiter{
+			Name:             fmt.Sprintf("domain %s", rs.Primary.ID),
+			Refresh:          domainStatusFunc(client, rs.Primary.ID),
+			Target:           targetStatus,
+			NotFoundIsTarget: true,
+			Timeout:          time.Minute,
+		}).Wait(context.Background())
 		if err != nil {
 			return fmt.Errorf("Domain still exists: %s ", err)
 		}
@@ -140,7 +147,7 @@ func testAccCheckKoyebDomainExists(n string, domain *koyeb.Domain) resource.Test
 			return fmt.Errorf("No Record ID is set")
 		}
 
-		client := testAccProvider.Meta().(*koyeb.APIClient)
+		client := testAccProvider.Meta().(*providerMeta).client
 
 		res, _, err := client.DomainsApi.GetDomain(context.Background(), rs.Primary.ID).Execute()
 
@@ -6,12 +6,12 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
-	"github.com/koyeb/koyeb-cli/pkg/koyeb/idmapper"
 )
 
 const (
@@ -115,6 +115,7 @@ func secretSchema() map[string]*schema.Schema {
 			Sensitive:     true,
 			ConflictsWith: registryTypes,
 		},
+		"deletion_protection": deletionProtectionSchema("secret"),
 		"updated_at": {
 			Type:        schema.TypeString,
 			Computed:    true,
@@ -208,9 +209,17 @@ func resourceKoyebSecret() *schema.Resource {
 		DeleteContext: resourceKoyebSecretDelete,
 
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importStateWithDeletionProtection,
 		},
 
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		CustomizeDiff: customizeDeletionProtectionDiff("secret", secretSchema()),
+
 		Schema: secretSchema(),
 	}
 }
@@ -250,7 +259,7 @@ func setSecretAttribute(d *schema.ResourceData, secret koyeb.Secret, secretValue
 }
 
 func resourceKoyebSecretCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	client := meta.(*providerMeta).client
 
 	secret := koyeb.CreateSecret{
 		Name: toOpt(d.Get("name").(string)),
@@ -285,25 +294,25 @@ func resourceKoyebSecretCreate(ctx context.Context, d *schema.ResourceData, meta
 		secret.AzureContainerRegistry = expandRegistry(azureContainerRegistry.(*schema.Set).List(), "azure_container_registry").(*koyeb.AzureContainerRegistryConfiguration)
 	}
 
-	res, resp, err := client.SecretsApi.CreateSecret(ctx).Secret(secret).Execute()
+	res, _, err := client.SecretsApi.CreateSecret(ctx).Secret(secret).Execute()
 	if err != nil {
-		return diag.Errorf("Error creating secret: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error creating secret", err, resourceKoyebSecret().Schema)
 	}
 
 	d.SetId(*res.Secret.Id)
+	meta.(*providerMeta).resolver.Invalidate(secretKind)
 	log.Printf("[INFO] Created secret name: %s", *res.Secret.Name)
 
 	return resourceKoyebSecretRead(ctx, d, meta)
 }
 
 func resourceKoyebSecretRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
-	mapper := idmapper.NewMapper(context.Background(), client)
-	secretMapper := mapper.Secret()
+	client := meta.(*providerMeta).client
+	resolver := meta.(*providerMeta).resolver
 	var secretId string
 
 	if d.Id() != "" {
-		id, err := secretMapper.ResolveID(d.Id())
+		id, err := resolver.ResolveID(ctx, secretKind, d.Id())
 
 		if err != nil {
 			return diag.Errorf("Error retrieving secret: %s", err)
@@ -312,23 +321,25 @@ func resourceKoyebSecretRead(ctx context.Context, d *schema.ResourceData, meta i
 		secretId = id
 	}
 
-	res, resp, err := client.SecretsApi.GetSecret(context.Background(), secretId).Execute()
+	res, resp, err := client.SecretsApi.GetSecret(ctx, secretId).Execute()
 	if err != nil {
 		// If the Secret is somehow already destroyed, mark as
 		// successfully gone
-		if resp.StatusCode == 404 {
+		if isNotFound(resp) {
 			d.SetId("")
 			return nil
 		}
 
-		return diag.Errorf("Error retrieving secret: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error retrieving secret", err, resourceKoyebSecret().Schema)
 	}
 
 	body := make(map[string]interface{})
-	_, resp, err = client.SecretsApi.RevealSecret(context.Background(), secretId).Body(body).Execute()
-	if resp.StatusCode != 200 && err != nil {
-		return diag.Errorf("Error retrieving secret value: %s", err)
-
+	_, resp, err = client.SecretsApi.RevealSecret(ctx, secretId).Body(body).Execute()
+	// The reply can't be decoded for simple secrets, whose value is a string
+	// rather than an object, so only errors without a successful response
+	// are reported
+	if err != nil && (resp == nil || resp.StatusCode != 200) {
+		return apiErrorDiagnostics("Error retrieving secret value", err, resourceKoyebSecret().Schema)
 	}
 
 	buffer, err := io.ReadAll(resp.Body)
@@ -352,7 +363,12 @@ func resourceKoyebSecretRead(ctx context.Context, d *schema.ResourceData, meta i
 }
 
 func resourceKoyebSecretUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	client := meta.(*providerMeta).client
+
+	// The deletion protection is only stored in the state
+	if !d.HasChangeExcept("deletion_protection") {
+		return resourceKoyebSecretRead(ctx, d, meta)
+	}
 
 	secret := koyeb.Secret{
 		Name: toOpt(d.Get("name").(string)),
@@ -387,10 +403,14 @@ func resourceKoyebSecretUpdate(ctx context.Context, d *schema.ResourceData, meta
 		secret.AzureContainerRegistry = expandRegistry(azureContainerRegistry.(*schema.Set).List(), "azure_container_registry").(*koyeb.AzureContainerRegistryConfiguration)
 	}
 
-	res, resp, err := client.SecretsApi.UpdateSecret(context.Background(), d.Id()).Secret(secret).Execute()
+	res, _, err := client.SecretsApi.UpdateSecret(ctx, d.Id()).Secret(secret).Execute()
 
 	if err != nil {
-		return diag.Errorf("Error updating secret: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error updating secret", err, resourceKoyebSecret().Schema)
+	}
+
+	if d.HasChange("name") {
+		meta.(*providerMeta).resolver.Invalidate(secretKind)
 	}
 
 	log.Printf("[INFO] Updated secret name: %s", *res.Secret.Name)
@@ -399,14 +419,20 @@ func resourceKoyebSecretUpdate(ctx context.Context, d *schema.ResourceData, meta
 }
 
 func resourceKoyebSecretDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	if diags := checkDeletionProtection(d, "secret"); diags.HasError() {
+		return diags
+	}
 
-	res, resp, err := client.SecretsApi.DeleteSecret(context.Background(), d.Id()).Execute()
+	client := meta.(*providerMeta).client
+
+	_, _, err := client.SecretsApi.DeleteSecret(ctx, d.Id()).Execute()
 
 	if err != nil {
-		return diag.Errorf("Error deleting secret: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error deleting secret", err, resourceKoyebSecret().Schema)
 	}
 
+	meta.(*providerMeta).resolver.Invalidate(secretKind)
+
 	d.SetId("")
 	return nil
 }
@@ -105,7 +105,7 @@ func TestAccKoyebSecret_Basic(t *testing.T) {
 }
 
 func testAccCheckKoyebSecretDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(*koyeb.APIClient)
+	client := testAccProvider.Meta().(*providerMeta).client
 
 	for _, rs := range s.RootModule().Resources {
 		if rs.Type != "koyeb_secret" {
@@ -132,7 +132,7 @@ func testAccCheckKoyebSecretAttributesMatch(resourceName, expectedName string) r
 			return fmt.Errorf("no Record ID is set")
 		}
 
-		client := testAccProvider.Meta().(*koyeb.APIClient)
+		client := testAccProvider.Meta().(*providerMeta).client
 		secret, _, err := client.SecretsApi.GetSecret(context.Background(), rs.Primary.ID).Execute()
 		if err != nil {
 			return fmt.Errorf("error retrieving secret: %w", err)
@@ -157,7 +157,7 @@ func testAccCheckKoyebSecretExists(n string, secret *koyeb.Secret) resource.Test
 			return fmt.Errorf("No Record ID is set")
 		}
 
-		client := testAccProvider.Meta().(*koyeb.APIClient)
+		client := testAccProvider.Meta().(*providerMeta).client
 
 		res, _, err := client.SecretsApi.GetSecret(context.Background(), rs.Primary.ID).Execute()
 
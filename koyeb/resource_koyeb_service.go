@@ -2,14 +2,21 @@ package koyeb
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
-	"github.com/koyeb/koyeb-cli/pkg/koyeb/idmapper"
+	"golang.org/x/exp/slices"
 )
 
 func serviceSchema() map[string]*schema.Schema {
@@ -44,6 +51,58 @@ func serviceSchema() map[string]*schema.Schema {
 			Description: "The service deployment definition",
 			Elem:        deploymentDefinitionSchena(),
 		},
+		"deletion_protection": deletionProtectionSchema("service"),
+		"wait_for_deployment": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     true,
+			Description: "If set to true, wait for the latest deployment of the service to become healthy when the service is created or updated",
+		},
+		"git_sha": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The commit SHA deployed by the latest deployment, for services deployed from git",
+		},
+		"archive_hash": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The hash of the content of the archive source directory. A new archive is uploaded and deployed when it changes. As the API doesn't expose the content of deployed archives, the first apply after an import only records the hash, without redeploying the service",
+		},
+		"app_domain": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The domain automatically assigned to the app of the service",
+		},
+		"public_urls": {
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+			Description: "The public URLs of the service, one for each domain of its app and path of the routes of its active deployment",
+		},
+		"private_hostname": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The hostname of the service on the private network of the organization, to reach it from the other services. The API doesn't expose it, so it is derived from the names of the service and of its app, as `<service>.<app>.koyeb`",
+		},
+		"redeploy_triggers": {
+			Type:        schema.TypeMap,
+			Optional:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+			Description: "A map of arbitrary values which, when changed, trigger a new deployment of the service with the same definition, for instance to pull the latest image of a mutable tag or to rebuild a git branch",
+		},
+		"redeploy_options": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Description: "The options used when the service is redeployed because of a change of `redeploy_triggers`",
+			Elem:        redeployOptionsSchema(),
+		},
+		"paused": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "If set to true, the service is paused and its instances are stopped until it is resumed",
+		},
 		"organization_id": {
 			Type:        schema.TypeString,
 			Computed:    true,
@@ -75,6 +134,18 @@ func serviceSchema() map[string]*schema.Schema {
 			Optional:    true,
 			Description: "The status messages of the service",
 		},
+		"regional_deployments": {
+			Type:        schema.TypeList,
+			Computed:    true,
+			Description: "The regional deployments of the active deployment of the service, one for each region",
+			Elem:        regionalDeploymentSchema(),
+		},
+		"instances": {
+			Type:        schema.TypeList,
+			Computed:    true,
+			Description: "The instances of the active deployment of the service which are not stopped",
+			Elem:        instanceSchema(),
+		},
 		"paused_at": {
 			Type:        schema.TypeString,
 			Computed:    true,
@@ -103,6 +174,93 @@ func serviceSchema() map[string]*schema.Schema {
 	}
 }
 
+func redeployOptionsSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"use_cache": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "If set to true, the build cache is used to build the new deployment",
+			},
+			"sha": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The git commit SHA to deploy, defaults to the latest commit of the branch",
+			},
+		},
+	}
+}
+
+func regionalDeploymentSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The regional deployment ID",
+			},
+			"region": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The region of the regional deployment",
+			},
+			"status": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The status of the regional deployment",
+			},
+			"messages": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The status messages of the regional deployment",
+			},
+			"healthy_instances": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of healthy instances in the region",
+			},
+		},
+	}
+}
+
+func instanceSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The instance ID",
+			},
+			"region": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The region of the instance",
+			},
+			"regional_deployment_id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The ID of the regional deployment of the instance",
+			},
+			"status": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The status of the instance",
+			},
+			"allocated_at": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The date and time of when the instance was allocated",
+			},
+			"restart_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of times the instance was restarted after a failure, counted from the failed instances it replaced",
+			},
+		},
+	}
+}
+
 func deploymentDefinitionSchena() *schema.Resource {
 	return &schema.Resource{
 		Schema: map[string]*schema.Schema{
@@ -123,6 +281,15 @@ func deploymentDefinitionSchena() *schema.Resource {
 				Description:  "The service type, either WEB or WORKER (default WEB)",
 				ValidateFunc: validation.StringInSlice([]string{"WEB", "WORKER"}, false),
 			},
+			"strategy": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Computed:    true,
+				Description: "The strategy used to replace the instances of the previous deployment, defaults to the platform default",
+				Elem:        strategySchema(),
+				Set:         schema.HashResource(strategySchema()),
+				MaxItems:    1,
+			},
 			"docker": {
 				Type:     schema.TypeSet,
 				Optional: true,
@@ -137,11 +304,19 @@ func deploymentDefinitionSchena() *schema.Resource {
 				Set:      schema.HashResource(gitSchema()),
 				MaxItems: 1,
 			},
-			"env": {
+			"archive": {
 				Type:     schema.TypeSet,
 				Optional: true,
-				Elem:     envSchema(),
-				Set:      schema.HashResource(envSchema()),
+				Elem:     archiveSchema(),
+				Set:      schema.HashResource(archiveSchema()),
+				MaxItems: 1,
+			},
+			"env": {
+				Type:             schema.TypeSet,
+				Optional:         true,
+				Elem:             envSchema(),
+				Set:              schema.HashResource(envSchema()),
+				DiffSuppressFunc: suppressEquivalentScopes("env", envSchema(), "scopes"),
 			},
 			"ports": {
 				Type:     schema.TypeSet,
@@ -157,6 +332,7 @@ func deploymentDefinitionSchena() *schema.Resource {
 			"health_checks": {
 				Type:     schema.TypeSet,
 				Optional: true,
+				Computed: true,
 				Elem:     healthCheckSchema(),
 				Set:      schema.HashResource(healthCheckSchema()),
 			},
@@ -167,18 +343,20 @@ func deploymentDefinitionSchena() *schema.Resource {
 				Set:      schema.HashResource(routeSchema()),
 			},
 			"instance_types": {
-				Type:     schema.TypeSet,
-				Required: true,
-				MinItems: 1,
-				Elem:     instanceTypeSchema(),
-				Set:      schema.HashResource(instanceTypeSchema()),
+				Type:             schema.TypeSet,
+				Required:         true,
+				MinItems:         1,
+				Elem:             instanceTypeSchema(),
+				Set:              schema.HashResource(instanceTypeSchema()),
+				DiffSuppressFunc: suppressEquivalentScopes("instance_types", instanceTypeSchema(), "scopes"),
 			},
 			"scalings": {
-				Type:     schema.TypeSet,
-				Required: true,
-				MinItems: 1,
-				Elem:     scalingSchema(),
-				Set:      schema.HashResource(scalingSchema()),
+				Type:             schema.TypeSet,
+				Required:         true,
+				MinItems:         1,
+				Elem:             scalingSchema(),
+				Set:              schema.HashResource(scalingSchema()),
+				DiffSuppressFunc: suppressEquivalentScopes("scalings", scalingSchema(), "scopes"),
 			},
 			"regions": {
 				Type:        schema.TypeSet,
@@ -187,11 +365,32 @@ func deploymentDefinitionSchena() *schema.Resource {
 				Elem:        &schema.Schema{Type: schema.TypeString},
 			},
 			"volumes": {
+				Type:             schema.TypeSet,
+				Optional:         true,
+				Description:      "The volumes to attach and mount to the service",
+				Elem:             serviceVolumeSchema(),
+				Set:              schema.HashResource(serviceVolumeSchema()),
+				DiffSuppressFunc: suppressEquivalentScopes("volumes", serviceVolumeSchema(), "scope"),
+			},
+			"config_file": {
 				Type:        schema.TypeSet,
 				Optional:    true,
-				Description: "The volumes to attach and mount to the service",
-				Elem:        serviceVolumeSchema(),
-				Set:         schema.HashResource(serviceVolumeSchema()),
+				Description: "The files to mount into the service instances",
+				Elem:        configFileSchema(),
+				Set:         schema.HashResource(configFileSchema()),
+			},
+		},
+	}
+}
+
+func strategySchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"type": {
+				Type:         schema.TypeString,
+				Required:     true,
+				Description:  "The deployment strategy, one of ROLLING, BLUE_GREEN, CANARY or IMMEDIATE. WORKER services can't be deployed with BLUE_GREEN",
+				ValidateFunc: validation.StringInSlice([]string{"ROLLING", "BLUE_GREEN", "CANARY", "IMMEDIATE"}, false),
 			},
 		},
 	}
@@ -247,8 +446,18 @@ func gitSchema() *schema.Resource {
 			},
 			"branch": {
 				Type:        schema.TypeString,
-				Required:    true,
-				Description: "The GitHub branch to deploy",
+				Optional:    true,
+				Description: "The GitHub branch to deploy. Exactly one of `branch`, `tag` and `sha` must be set",
+			},
+			"tag": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The GitHub tag to deploy. Exactly one of `branch`, `tag` and `sha` must be set",
+			},
+			"sha": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The commit SHA to deploy. Exactly one of `branch`, `tag` and `sha` must be set",
 			},
 			"workdir": {
 				Type:        schema.TypeString,
@@ -279,6 +488,38 @@ func gitSchema() *schema.Resource {
 	}
 }
 
+func archiveSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path of the local directory to archive and deploy",
+			},
+			"ignore_patterns": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The patterns of the files and directories not to archive, matched against their path relative to `path` and their name. `.git` directories are always ignored",
+			},
+			"buildpack": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem:     buildpackBuilderSchema(),
+				Set:      schema.HashResource(buildpackBuilderSchema()),
+				MaxItems: 1,
+			},
+			"dockerfile": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem:     dockerBuilderSchema(),
+				Set:      schema.HashResource(dockerBuilderSchema()),
+				MaxItems: 1,
+			},
+		},
+	}
+}
+
 func buildpackBuilderSchema() *schema.Resource {
 	return &schema.Resource{
 		Schema: map[string]*schema.Schema{
@@ -336,6 +577,7 @@ func dockerBuilderSchema() *schema.Resource {
 			"privileged": {
 				Type:        schema.TypeBool,
 				Optional:    true,
+				Default:     false,
 				Description: "When enabled, the service container will run in privileged mode. This advanced feature is useful to get advanced system privileges.",
 			},
 		},
@@ -346,11 +588,11 @@ func envSchema() *schema.Resource {
 	return &schema.Resource{
 		Schema: map[string]*schema.Schema{
 			"scopes": {
-				Type:     schema.TypeList,
+				Type:     schema.TypeSet,
 				Optional: true,
 				// Computed:    true,
-				Description: "The regions the environment variable needs to be exposed",
-				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The regions the environment variable needs to be exposed, as region names or `region:<name>`",
+				Elem:        scopeSchema(),
 			},
 			"key": {
 				Type:        schema.TypeString,
@@ -401,21 +643,25 @@ func healthCheckSchema() *schema.Resource {
 			"grace_period": {
 				Type:        schema.TypeInt,
 				Optional:    true,
+				Default:     5,
 				Description: "The period in seconds to wait for the instance to become healthy, default is 5s",
 			},
 			"interval": {
 				Type:        schema.TypeInt,
 				Optional:    true,
+				Default:     60,
 				Description: "The period in seconds between two health checks, default is 60s",
 			},
 			"restart_limit": {
 				Type:        schema.TypeInt,
 				Optional:    true,
+				Default:     3,
 				Description: "The number of consecutive failures before attempting to restart the service, default is 3",
 			},
 			"timeout": {
 				Type:        schema.TypeInt,
 				Optional:    true,
+				Default:     5,
 				Description: "The maximum time to wait in seconds before considering the check as a failure, default is 5s",
 			},
 			"tcp": {
@@ -466,6 +712,7 @@ func HTTPHealthCheckSchema() *schema.Resource {
 			"method": {
 				Type:        schema.TypeString,
 				Optional:    true,
+				Default:     "GET",
 				Description: "An optional HTTP method to use to perform the health check, default is GET",
 			},
 			"headers": {
@@ -517,10 +764,10 @@ func instanceTypeSchema() *schema.Resource {
 	return &schema.Resource{
 		Schema: map[string]*schema.Schema{
 			"scopes": {
-				Type:        schema.TypeList,
+				Type:        schema.TypeSet,
 				Optional:    true,
-				Description: "The regions to use the instance type",
-				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The regions to use the instance type, as region names or `region:<name>`",
+				Elem:        scopeSchema(),
 			},
 			"type": {
 				Type:        schema.TypeString,
@@ -535,22 +782,24 @@ func scalingSchema() *schema.Resource {
 	return &schema.Resource{
 		Schema: map[string]*schema.Schema{
 			"scopes": {
-				Type:        schema.TypeList,
+				Type:        schema.TypeSet,
 				Optional:    true,
-				Description: "The regions to apply the scaling configuration",
-				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The regions to apply the scaling configuration, as region names or `region:<name>`",
+				Elem:        scopeSchema(),
 			},
 			"min": {
-				Type:        schema.TypeInt,
-				Optional:    true,
-				Default:     1,
-				Description: "The minimal number of instances to use to support your service",
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      1,
+				Description:  "The minimal number of instances to use to support your service, 0 to scale the service to zero when it receives no request",
+				ValidateFunc: validation.IntAtLeast(0),
 			},
 			"max": {
-				Type:        schema.TypeInt,
-				Optional:    true,
-				Default:     1,
-				Description: "The maximum number of instance to use to support your service",
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      1,
+				Description:  "The maximum number of instance to use to support your service",
+				ValidateFunc: validation.IntAtLeast(1),
 			},
 			"targets": {
 				Type:     schema.TypeSet,
@@ -600,6 +849,13 @@ func autoScalingTargetSchema() *schema.Resource {
 				Elem:        autoScalingTargetValueSchema(),
 				Set:         schema.HashResource(autoScalingTargetValueSchema()),
 			},
+			"sleep_idle_delay": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "The delay in seconds after which the instances of a service which received no request are put to sleep, when `min` is 0",
+				Elem:        autoScalingTargetValueSchema(),
+				Set:         schema.HashResource(autoScalingTargetValueSchema()),
+			},
 		},
 	}
 }
@@ -622,8 +878,8 @@ func serviceVolumeSchema() *schema.Resource {
 			"scope": {
 				Type:        schema.TypeList,
 				Optional:    true,
-				Description: "The regions to apply the scaling configuration",
-				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The regions to mount the volume in, as region names or `region:<name>`",
+				Elem:        scopeSchema(),
 			},
 			"id": {
 				Type:        schema.TypeString,
@@ -644,6 +900,263 @@ func serviceVolumeSchema() *schema.Resource {
 	}
 }
 
+func configFileSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The absolute path where to mount the file",
+			},
+			"content": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The content of the file, for instance rendered with `templatefile()`",
+			},
+			"permissions": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "0644",
+				Description:  "The permissions of the file in octal notation (default 0644)",
+				ValidateFunc: validation.StringMatch(regexp.MustCompile(`^0[0-7]{3}$`), "must be an octal mode, for instance 0644"),
+			},
+		},
+	}
+}
+
+func scopeSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeString,
+		ValidateFunc: validation.StringMatch(regexp.MustCompile(`^(region:)?[a-z0-9-]+$`), "must be a region name, optionally prefixed with region:"),
+	}
+}
+
+// expandScopes returns the scopes in the region:<name> form expected by the
+// API.
+func expandScopes(config []interface{}) []string {
+	scopes := make([]string, len(config))
+	for i, v := range config {
+		scopes[i] = v.(string)
+		if !strings.HasPrefix(scopes[i], "region:") {
+			scopes[i] = "region:" + scopes[i]
+		}
+	}
+	return scopes
+}
+
+// effectiveScopes returns the sorted regions covered by the scopes, an empty
+// list of scopes covering all the regions of the deployment.
+func effectiveScopes(config []interface{}, regions []string) []string {
+	var scopes []string
+	if len(config) == 0 {
+		for _, region := range regions {
+			scopes = append(scopes, "region:"+region)
+		}
+	} else {
+		scopes = expandScopes(config)
+	}
+
+	slices.Sort(scopes)
+	return slices.Compact(scopes)
+}
+
+// flattenScopes returns the scopes to store in the state. The API expands an
+// empty list of scopes to all the regions of the deployment, so scopes
+// covering exactly the deployment regions are flattened back to an empty list.
+func flattenScopes(scopes []string, regions []string) []string {
+	if len(scopes) != len(regions) {
+		return scopes
+	}
+
+	for _, region := range regions {
+		if !slices.Contains(scopes, "region:"+region) {
+			return scopes
+		}
+	}
+
+	return []string{}
+}
+
+// scopedDefinitionAttributes are the attributes of the definition whose
+// elements are scoped to regions, with the key of their scopes.
+var scopedDefinitionAttributes = map[string]string{
+	"env":            "scopes",
+	"instance_types": "scopes",
+	"scalings":       "scopes",
+	"volumes":        "scope",
+}
+
+// suppressEquivalentScopes returns a DiffSuppressFunc for the elements of the
+// definition attribute key, scoped by their scopesKey attribute. The diff is
+// suppressed when the elements only differ by how their scopes are written,
+// for instance as region names rather than region:<name>, or as an empty list
+// rather than all the regions of the service, which the API returns.
+func suppressEquivalentScopes(key string, elem *schema.Resource, scopesKey string) schema.SchemaDiffSuppressFunc {
+	return func(k, old, new string, d *schema.ResourceData) bool {
+		oldElements, newElements := d.GetChange("definition.0." + key)
+		// Scopes are compared in the new regions, an empty list of scopes
+		// covering other regions when the regions change
+		regions := expandRegions(d.Get("definition.0.regions").(*schema.Set).List())
+
+		return equivalentScopedElements(oldElements.(*schema.Set), newElements.(*schema.Set), elem, scopesKey, regions)
+	}
+}
+
+// equivalentScopedElements returns whether the elements only differ by how
+// their scopes, stored under scopesKey, are written.
+func equivalentScopedElements(oldElements *schema.Set, newElements *schema.Set, elem *schema.Resource, scopesKey string, regions []string) bool {
+	oldHashes, ok := scopedElementHashes(oldElements, elem, scopesKey, regions)
+	if !ok {
+		return false
+	}
+	newHashes, ok := scopedElementHashes(newElements, elem, scopesKey, regions)
+	if !ok {
+		return false
+	}
+	return slices.Equal(oldHashes, newHashes)
+}
+
+// scopedElementHashes returns the sorted hashes of the elements, with their
+// scopes replaced by the regions they cover. It returns false when a scope
+// can't be read, which happens for changed lists nested in sets.
+func scopedElementHashes(elements *schema.Set, elem *schema.Resource, scopesKey string, regions []string) ([]int, bool) {
+	hash := schema.HashResource(elem)
+
+	hashes := make([]int, 0, elements.Len())
+	for _, rawElement := range elements.List() {
+		element := make(map[string]interface{})
+		for k, v := range rawElement.(map[string]interface{}) {
+			element[k] = v
+		}
+
+		config := scopeList(element[scopesKey])
+		for _, scope := range config {
+			if _, ok := scope.(string); !ok {
+				return nil, false
+			}
+		}
+
+		var scopes []interface{}
+		for _, scope := range effectiveScopes(config, regions) {
+			scopes = append(scopes, scope)
+		}
+		if set, ok := element[scopesKey].(*schema.Set); ok {
+			element[scopesKey] = schema.NewSet(set.F, scopes)
+		} else {
+			element[scopesKey] = scopes
+		}
+
+		hashes = append(hashes, hash(element))
+	}
+
+	sort.Ints(hashes)
+	return hashes, true
+}
+
+// scopeList returns the scopes of an element, stored either as a set or as a
+// list.
+func scopeList(scopes interface{}) []interface{} {
+	if set, ok := scopes.(*schema.Set); ok {
+		return set.List()
+	}
+	return scopes.([]interface{})
+}
+
+// validatePorts checks that the routes and the health checks use exposed
+// ports, and that WORKER services have no route.
+func validatePorts(d *schema.ResourceDiff) error {
+	for _, key := range []string{"definition.0.type", "definition.0.ports", "definition.0.routes"} {
+		if !d.NewValueKnown(key) {
+			return nil
+		}
+	}
+
+	ports := map[int]bool{}
+	for _, rawPort := range d.Get("definition.0.ports").(*schema.Set).List() {
+		ports[rawPort.(map[string]interface{})["port"].(int)] = true
+	}
+
+	routes := d.Get("definition.0.routes").(*schema.Set).List()
+	if len(routes) > 0 && d.Get("definition.0.type").(string) == "WORKER" {
+		return fmt.Errorf("definition.0.routes: WORKER services can't have routes")
+	}
+	for _, rawRoute := range routes {
+		if port := rawRoute.(map[string]interface{})["port"].(int); !ports[port] {
+			return fmt.Errorf("definition.0.routes: port %d is not exposed in ports", port)
+		}
+	}
+
+	// Health checks which aren't configured are computed from the ports by
+	// the API, and still hold the previous ports when these change
+	if !d.NewValueKnown("definition.0.health_checks") || !healthChecksConfigured(d) {
+		return nil
+	}
+	for _, rawHealthCheck := range d.Get("definition.0.health_checks").(*schema.Set).List() {
+		healthCheck := rawHealthCheck.(map[string]interface{})
+		for _, key := range []string{"tcp", "http"} {
+			for _, rawCheck := range healthCheck[key].(*schema.Set).List() {
+				if port := rawCheck.(map[string]interface{})["port"].(int); !ports[port] {
+					return fmt.Errorf("definition.0.health_checks: port %d is not exposed in ports", port)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// healthChecksConfigured returns whether the health checks are set in the
+// configuration. Without the raw configuration, the planned health checks
+// are only the configured ones when the service is created.
+func healthChecksConfigured(d *schema.ResourceDiff) bool {
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return d.Id() == ""
+	}
+
+	definition := config.GetAttr("definition")
+	if definition.IsNull() || !definition.IsKnown() || definition.LengthInt() == 0 {
+		return false
+	}
+	healthChecks := definition.Index(cty.NumberIntVal(0)).GetAttr("health_checks")
+	return !healthChecks.IsNull() && healthChecks.IsKnown() && healthChecks.LengthInt() > 0
+}
+
+// validateScopes checks that no environment variable, instance type or
+// scaling is defined more than once for the same region.
+func validateScopes(d *schema.ResourceDiff) error {
+	if !d.NewValueKnown("definition.0.regions") {
+		return nil
+	}
+	regions := expandRegions(d.Get("definition.0.regions").(*schema.Set).List())
+
+	for _, attribute := range []string{"env", "instance_types", "scalings"} {
+		key := "definition.0." + attribute
+		if !d.NewValueKnown(key) {
+			continue
+		}
+
+		defined := map[string]bool{}
+		for _, rawElement := range d.Get(key).(*schema.Set).List() {
+			element := rawElement.(map[string]interface{})
+			name, _ := element["key"].(string)
+
+			for _, scope := range effectiveScopes(element["scopes"].(*schema.Set).List(), regions) {
+				switch {
+				case !defined[name+"/"+scope]:
+					defined[name+"/"+scope] = true
+				case attribute == "env":
+					return fmt.Errorf("%s: %s is defined more than once for %s", key, name, scope)
+				default:
+					return fmt.Errorf("%s: more than one is defined for %s", key, scope)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 func expandEnvs(config []interface{}) []koyeb.DeploymentEnv {
 	envs := make([]koyeb.DeploymentEnv, 0, len(config))
 
@@ -654,12 +1167,7 @@ func expandEnvs(config []interface{}) []koyeb.DeploymentEnv {
 			Key: toOpt(env["key"].(string)),
 		}
 
-		rawScopes := env["scopes"].([]interface{})
-		scopes := make([]string, len(rawScopes))
-		for i, v := range rawScopes {
-			scopes[i] = v.(string)
-		}
-		e.Scopes = scopes
+		e.Scopes = expandScopes(env["scopes"].(*schema.Set).List())
 
 		if env["value"] != nil && env["value"].(string) != "" {
 			e.Value = toOpt(env["value"].(string))
@@ -674,14 +1182,14 @@ func expandEnvs(config []interface{}) []koyeb.DeploymentEnv {
 	return envs
 }
 
-func flattenEnvs(envs *[]koyeb.DeploymentEnv) []map[string]interface{} {
+func flattenEnvs(envs *[]koyeb.DeploymentEnv, regions []string) []map[string]interface{} {
 	result := make([]map[string]interface{}, len(*envs))
 
 	for i, env := range *envs {
 		r := make(map[string]interface{})
 
 		r["key"] = env.GetKey()
-		// r["scopes"] = env.GetScopes()
+		r["scopes"] = flattenScopes(env.GetScopes(), regions)
 
 		if value, ok := env.GetValueOk(); ok {
 			r["value"] = value
@@ -719,8 +1227,8 @@ func flattenPorts(ports *[]koyeb.DeploymentPort) []map[string]interface{} {
 	for i, port := range *ports {
 		r := make(map[string]interface{})
 
-		r["port"] = *port.Port
-		r["protocol"] = *port.Protocol
+		r["port"] = int(port.GetPort())
+		r["protocol"] = port.GetProtocol()
 
 		result[i] = r
 	}
@@ -751,7 +1259,7 @@ func flattenRoutes(routes *[]koyeb.DeploymentRoute) []map[string]interface{} {
 	for i, route := range *routes {
 		r := make(map[string]interface{})
 
-		r["port"] = route.GetPort()
+		r["port"] = int(route.GetPort())
 		r["path"] = route.GetPath()
 
 		result[i] = r
@@ -770,12 +1278,7 @@ func expandInstanceTypes(config []interface{}) []koyeb.DeploymentInstanceType {
 			Type: toOpt(instanceType["type"].(string)),
 		}
 
-		rawScopes := instanceType["scopes"].([]interface{})
-		scopes := make([]string, len(rawScopes))
-		for i, v := range rawScopes {
-			scopes[i] = v.(string)
-		}
-		r.Scopes = scopes
+		r.Scopes = expandScopes(instanceType["scopes"].(*schema.Set).List())
 
 		instanceTypes = append(instanceTypes, r)
 	}
@@ -783,14 +1286,14 @@ func expandInstanceTypes(config []interface{}) []koyeb.DeploymentInstanceType {
 	return instanceTypes
 }
 
-func flattenInstanceTypes(instanceTypes *[]koyeb.DeploymentInstanceType) []map[string]interface{} {
+func flattenInstanceTypes(instanceTypes *[]koyeb.DeploymentInstanceType, regions []string) []map[string]interface{} {
 	result := make([]map[string]interface{}, len(*instanceTypes))
 
 	for i, instanceType := range *instanceTypes {
 		r := make(map[string]interface{})
 
 		r["type"] = instanceType.GetType()
-		// r["scopes"] = instanceType.GetScopes()
+		r["scopes"] = flattenScopes(instanceType.GetScopes(), regions)
 
 		result[i] = r
 	}
@@ -809,12 +1312,7 @@ func expandScalings(config []interface{}) []koyeb.DeploymentScaling {
 			Min: toOpt(int64(scaling["min"].(int))),
 		}
 
-		rawScopes := scaling["scopes"].([]interface{})
-		scopes := make([]string, len(rawScopes))
-		for i, v := range rawScopes {
-			scopes[i] = v.(string)
-		}
-		s.Scopes = scopes
+		s.Scopes = expandScopes(scaling["scopes"].(*schema.Set).List())
 
 		targets := scaling["targets"].(*schema.Set).List()
 		for _, rawTarget := range targets {
@@ -879,6 +1377,18 @@ func expandScalings(config []interface{}) []koyeb.DeploymentScaling {
 				}
 			}
 
+			if target["sleep_idle_delay"] != nil {
+				sleepIdleDelay := target["sleep_idle_delay"].(*schema.Set).List()
+				for _, rawSleepIdleDelay := range sleepIdleDelay {
+					sleepIdleDelay := rawSleepIdleDelay.(map[string]interface{})
+					s.Targets = append(s.Targets, koyeb.DeploymentScalingTarget{
+						SleepIdleDelay: &koyeb.DeploymentScalingTargetSleepIdleDelay{
+							Value: toOpt(int64(sleepIdleDelay["value"].(int))),
+						},
+					})
+				}
+			}
+
 		}
 
 		scalings = append(scalings, s)
@@ -887,15 +1397,15 @@ func expandScalings(config []interface{}) []koyeb.DeploymentScaling {
 	return scalings
 }
 
-func flattenScalings(scalings *[]koyeb.DeploymentScaling) []map[string]interface{} {
+func flattenScalings(scalings *[]koyeb.DeploymentScaling, regions []string) []map[string]interface{} {
 	result := make([]map[string]interface{}, len(*scalings))
 
 	for i, scaling := range *scalings {
 		r := make(map[string]interface{})
 
-		r["max"] = scaling.GetMax()
-		r["min"] = scaling.GetMin()
-		// r["scopes"] = scaling.GetScopes()
+		r["max"] = int(scaling.GetMax())
+		r["min"] = int(scaling.GetMin())
+		r["scopes"] = flattenScopes(scaling.GetScopes(), regions)
 
 		targetMap := make(map[string]interface{})
 		for _, target := range scaling.Targets {
@@ -950,12 +1460,24 @@ func flattenScalings(scalings *[]koyeb.DeploymentScaling) []map[string]interface
 					},
 				)
 			}
+			if sleepIdleDelay, ok := target.GetSleepIdleDelayOk(); ok {
+				targetMap["sleep_idle_delay"] = schema.NewSet(
+					schema.HashResource(autoScalingTargetValueSchema()),
+					[]interface{}{
+						map[string]interface{}{
+							"value": int(sleepIdleDelay.GetValue()),
+						},
+					},
+				)
+			}
 
 		}
-		r["targets"] = schema.NewSet(
-			schema.HashResource(autoScalingTargetSchema()),
-			[]interface{}{targetMap},
-		)
+		if len(targetMap) > 0 {
+			r["targets"] = schema.NewSet(
+				schema.HashResource(autoScalingTargetSchema()),
+				[]interface{}{targetMap},
+			)
+		}
 		result[i] = r
 	}
 
@@ -1002,12 +1524,12 @@ func flattenDocker(dockerSource *koyeb.DockerSource) []interface{} {
 	result := make([]interface{}, 0)
 
 	r := make(map[string]interface{})
-	r["image"] = dockerSource.Image
-	r["command"] = dockerSource.Command
-	r["args"] = dockerSource.Args
-	r["entrypoint"] = dockerSource.Entrypoint
-	r["privileged"] = dockerSource.Privileged
-	r["image_registry_secret"] = dockerSource.ImageRegistrySecret
+	r["image"] = dockerSource.GetImage()
+	r["command"] = dockerSource.GetCommand()
+	r["args"] = dockerSource.GetArgs()
+	r["entrypoint"] = dockerSource.GetEntrypoint()
+	r["privileged"] = dockerSource.GetPrivileged()
+	r["image_registry_secret"] = dockerSource.GetImageRegistrySecret()
 
 	result = append(result, r)
 
@@ -1056,11 +1578,12 @@ func flattenDockerBuilder(dockerBuilderSource *koyeb.DockerBuilder) []interface{
 	result := make([]interface{}, 0)
 
 	r := make(map[string]interface{})
-	r["entrypoint"] = dockerBuilderSource.Entrypoint
-	r["command"] = dockerBuilderSource.Command
-	r["args"] = dockerBuilderSource.Args
-	r["target"] = dockerBuilderSource.Target
-	r["privileged"] = dockerBuilderSource.Privileged
+	r["dockerfile"] = dockerBuilderSource.GetDockerfile()
+	r["entrypoint"] = dockerBuilderSource.GetEntrypoint()
+	r["command"] = dockerBuilderSource.GetCommand()
+	r["args"] = dockerBuilderSource.GetArgs()
+	r["target"] = dockerBuilderSource.GetTarget()
+	r["privileged"] = dockerBuilderSource.GetPrivileged()
 
 	result = append(result, r)
 
@@ -1105,11 +1628,20 @@ func expandGitSource(config []interface{}) *koyeb.GitSource {
 
 	gitSource := &koyeb.GitSource{
 		Repository:     toOpt(rawGitSource["repository"].(string)),
-		Branch:         toOpt(rawGitSource["branch"].(string)),
 		Workdir:        toOpt(rawGitSource["workdir"].(string)),
 		NoDeployOnPush: toOpt(rawGitSource["no_deploy_on_push"].(bool)),
 	}
 
+	if branch := rawGitSource["branch"].(string); branch != "" {
+		gitSource.Branch = toOpt(branch)
+	}
+	if tag := rawGitSource["tag"].(string); tag != "" {
+		gitSource.Tag = toOpt(tag)
+	}
+	if sha := rawGitSource["sha"].(string); sha != "" {
+		gitSource.Sha = toOpt(sha)
+	}
+
 	if rawGitSource["dockerfile"] != nil && rawGitSource["dockerfile"].(*schema.Set).Len() > 0 {
 		gitSource.Docker = expandDockerBuilder(rawGitSource["dockerfile"].(*schema.Set).List())
 	} else if rawGitSource["buildpack"] != nil && rawGitSource["buildpack"].(*schema.Set).Len() > 0 {
@@ -1125,6 +1657,8 @@ func flattenGit(gitSource *koyeb.GitSource) []interface{} {
 	r := make(map[string]interface{})
 	r["repository"] = gitSource.GetRepository()
 	r["branch"] = gitSource.GetBranch()
+	r["tag"] = gitSource.GetTag()
+	r["sha"] = gitSource.GetSha()
 	r["workdir"] = gitSource.GetWorkdir()
 	r["no_deploy_on_push"] = gitSource.GetNoDeployOnPush()
 	if buildpack, ok := gitSource.GetBuildpackOk(); ok {
@@ -1139,6 +1673,77 @@ func flattenGit(gitSource *koyeb.GitSource) []interface{} {
 	return result
 }
 
+func expandArchiveSource(config []interface{}) *koyeb.ArchiveSource {
+	rawArchiveSource := config[0].(map[string]interface{})
+
+	archiveSource := &koyeb.ArchiveSource{}
+
+	if rawArchiveSource["dockerfile"] != nil && rawArchiveSource["dockerfile"].(*schema.Set).Len() > 0 {
+		archiveSource.Docker = expandDockerBuilder(rawArchiveSource["dockerfile"].(*schema.Set).List())
+	} else if rawArchiveSource["buildpack"] != nil && rawArchiveSource["buildpack"].(*schema.Set).Len() > 0 {
+		archiveSource.Buildpack = expandBuildpackBuilder(rawArchiveSource["buildpack"].(*schema.Set).List())
+	}
+
+	return archiveSource
+}
+
+// flattenArchive flattens the archive source. The API only knows the ID of
+// the uploaded archive, so the local path and ignore patterns are left empty
+// and kept from the configuration by setServiceAttribute.
+func flattenArchive(archiveSource *koyeb.ArchiveSource) []interface{} {
+	result := make([]interface{}, 0)
+
+	r := make(map[string]interface{})
+	r["path"] = ""
+	r["ignore_patterns"] = []interface{}{}
+	if buildpack, ok := archiveSource.GetBuildpackOk(); ok {
+		r["buildpack"] = flattenBuildpackBuilder(buildpack)
+	}
+	if docker, ok := archiveSource.GetDockerOk(); ok {
+		r["dockerfile"] = flattenDockerBuilder(docker)
+	}
+
+	result = append(result, r)
+
+	return result
+}
+
+// archiveSourceConfig returns the path and ignore patterns of the archive
+// source of the definition, if any.
+func archiveSourceConfig(definition map[string]interface{}) (string, []string, bool) {
+	archive := definition["archive"].(*schema.Set).List()
+	if len(archive) == 0 {
+		return "", nil, false
+	}
+
+	rawArchiveSource := archive[0].(map[string]interface{})
+	var ignorePatterns []string
+	for _, pattern := range rawArchiveSource["ignore_patterns"].(*schema.Set).List() {
+		ignorePatterns = append(ignorePatterns, pattern.(string))
+	}
+
+	return rawArchiveSource["path"].(string), ignorePatterns, true
+}
+
+func expandStrategy(config []interface{}) *koyeb.DeploymentStrategy {
+	rawStrategy := config[0].(map[string]interface{})
+
+	return &koyeb.DeploymentStrategy{
+		Type: toOpt(koyeb.DeploymentStrategyType("DEPLOYMENT_STRATEGY_TYPE_" + rawStrategy["type"].(string))),
+	}
+}
+
+func flattenStrategy(strategy *koyeb.DeploymentStrategy) []interface{} {
+	result := make([]interface{}, 0)
+
+	r := make(map[string]interface{})
+	r["type"] = strings.TrimPrefix(string(strategy.GetType()), "DEPLOYMENT_STRATEGY_TYPE_")
+
+	result = append(result, r)
+
+	return result
+}
+
 func expandHealthChecks(config []interface{}) []koyeb.DeploymentHealthCheck {
 	healthChecks := make([]koyeb.DeploymentHealthCheck, 0, len(config))
 
@@ -1218,10 +1823,10 @@ func flattenHealthChecks(healthChecks *[]koyeb.DeploymentHealthCheck) []map[stri
 	for i, check := range *healthChecks {
 		r := make(map[string]interface{})
 
-		r["grace_period"] = check.GetGracePeriod()
-		r["interval"] = check.GetInterval()
-		r["restart_limit"] = check.GetRestartLimit()
-		r["timeout"] = check.GetTimeout()
+		r["grace_period"] = int(check.GetGracePeriod())
+		r["interval"] = int(check.GetInterval())
+		r["restart_limit"] = int(check.GetRestartLimit())
+		r["timeout"] = int(check.GetTimeout())
 
 		if tcp, ok := check.GetTcpOk(); ok {
 			tcpEntry := map[string]interface{}{
@@ -1235,10 +1840,15 @@ func flattenHealthChecks(healthChecks *[]koyeb.DeploymentHealthCheck) []map[stri
 		}
 
 		if http, ok := check.GetHttpOk(); ok {
+			method := http.GetMethod()
+			if method == "" {
+				method = "GET"
+			}
+
 			httpEntry := map[string]interface{}{
 				"port":   int(http.GetPort()),
 				"path":   http.GetPath(),
-				"method": http.GetMethod(),
+				"method": method,
 			}
 
 			headers := flattenHTTPHealthCheckHeaders(http.GetHeaders())
@@ -1275,12 +1885,7 @@ func expandVolumes(config []interface{}) []koyeb.DeploymentVolume {
 			ReplicaIndex: toOpt(int64(volume["replica_index"].(int))),
 		}
 
-		rawScopes := volume["scopes"].([]interface{})
-		scopes := make([]string, len(rawScopes))
-		for i, v := range rawScopes {
-			scopes[i] = v.(string)
-		}
-		v.Scopes = scopes
+		v.Scopes = expandScopes(volume["scope"].([]interface{}))
 
 		volumes = append(volumes, v)
 	}
@@ -1288,7 +1893,7 @@ func expandVolumes(config []interface{}) []koyeb.DeploymentVolume {
 	return volumes
 }
 
-func flattenVolumes(volumes *[]koyeb.DeploymentVolume) []map[string]interface{} {
+func flattenVolumes(volumes *[]koyeb.DeploymentVolume, regions []string) []map[string]interface{} {
 	result := make([]map[string]interface{}, len(*volumes))
 
 	for i, volume := range *volumes {
@@ -1296,8 +1901,8 @@ func flattenVolumes(volumes *[]koyeb.DeploymentVolume) []map[string]interface{}
 
 		r["id"] = volume.GetId()
 		r["path"] = volume.GetPath()
-		r["replica_index"] = volume.GetReplicaIndex()
-		// r["scopes"] = volume.GetScopes()
+		r["replica_index"] = int(volume.GetReplicaIndex())
+		r["scope"] = flattenScopes(volume.GetScopes(), regions)
 
 		result[i] = r
 	}
@@ -1305,6 +1910,44 @@ func flattenVolumes(volumes *[]koyeb.DeploymentVolume) []map[string]interface{}
 	return result
 }
 
+func expandConfigFiles(config []interface{}) []koyeb.DeploymentFileMount {
+	files := make([]koyeb.DeploymentFileMount, 0, len(config))
+
+	for _, rawFile := range config {
+		file := rawFile.(map[string]interface{})
+
+		files = append(files, koyeb.DeploymentFileMount{
+			Path:        toOpt(file["path"].(string)),
+			Permissions: toOpt(file["permissions"].(string)),
+			Raw:         &koyeb.RawSource{Content: toOpt(file["content"].(string))},
+		})
+	}
+
+	return files
+}
+
+func flattenConfigFiles(files *[]koyeb.DeploymentFileMount) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(*files))
+
+	for _, file := range *files {
+		// Files mounted from secrets are not managed by the provider
+		raw, ok := file.GetRawOk()
+		if !ok {
+			continue
+		}
+
+		r := make(map[string]interface{})
+
+		r["path"] = file.GetPath()
+		r["content"] = raw.GetContent()
+		r["permissions"] = file.GetPermissions()
+
+		result = append(result, r)
+	}
+
+	return result
+}
+
 func expandRegions(regions []interface{}) []string {
 	expandedRegions := make([]string, len(regions))
 	for i, v := range regions {
@@ -1323,6 +1966,22 @@ func flattenRegions(regions *[]string) *schema.Set {
 	return flattenedRegions
 }
 
+func expandRedeployOptions(config []interface{}) koyeb.RedeployRequestInfo {
+	info := koyeb.RedeployRequestInfo{UseCache: toOpt(false)}
+
+	if len(config) == 0 || config[0] == nil {
+		return info
+	}
+
+	rawOptions := config[0].(map[string]interface{})
+	info.UseCache = toOpt(rawOptions["use_cache"].(bool))
+	if sha := rawOptions["sha"].(string); sha != "" {
+		info.Sha = toOpt(sha)
+	}
+
+	return info
+}
+
 func expandDeploymentDefinition(configmap map[string]interface{}) *koyeb.DeploymentDefinition {
 	rawDeploymentDefinition := configmap
 
@@ -1337,6 +1996,7 @@ func expandDeploymentDefinition(configmap map[string]interface{}) *koyeb.Deploym
 		Regions:       expandRegions(rawDeploymentDefinition["regions"].(*schema.Set).List()),
 		HealthChecks:  expandHealthChecks(rawDeploymentDefinition["health_checks"].(*schema.Set).List()),
 		Volumes:       expandVolumes(rawDeploymentDefinition["volumes"].(*schema.Set).List()),
+		FileMounts:    expandConfigFiles(rawDeploymentDefinition["config_file"].(*schema.Set).List()),
 	}
 
 	git := rawDeploymentDefinition["git"].(*schema.Set).List()
@@ -1349,32 +2009,51 @@ func expandDeploymentDefinition(configmap map[string]interface{}) *koyeb.Deploym
 		deploymentDefinition.Docker = expandDockerSource(docker)
 	}
 
+	archive := rawDeploymentDefinition["archive"].(*schema.Set).List()
+	if len(archive) > 0 {
+		deploymentDefinition.Archive = expandArchiveSource(archive)
+	}
+
+	strategy := rawDeploymentDefinition["strategy"].(*schema.Set).List()
+	if len(strategy) > 0 {
+		deploymentDefinition.Strategy = expandStrategy(strategy)
+	}
+
 	return deploymentDefinition
 }
 
 func flattenDeploymentDefinition(deployment *koyeb.DeploymentDefinition) []interface{} {
 	result := make([]interface{}, 0)
 
+	regions := deployment.GetRegions()
+
 	r := make(map[string]interface{})
 	r["name"] = deployment.GetName()
-	r["type"] = deployment.GetType()
+	r["type"] = string(deployment.GetType())
+	if strategy, ok := deployment.GetStrategyOk(); ok && strategy.GetType() != koyeb.DEPLOYMENTSTRATEGYTYPE_INVALID {
+		r["strategy"] = flattenStrategy(strategy)
+	}
 	if docker, ok := deployment.GetDockerOk(); ok && docker != nil {
 		r["docker"] = flattenDocker(docker)
 	}
 	if git, ok := deployment.GetGitOk(); ok && git != nil {
 		r["git"] = flattenGit(git)
 	}
-	r["env"] = flattenEnvs(toOpt(deployment.GetEnv()))
+	if archive, ok := deployment.GetArchiveOk(); ok && archive != nil {
+		r["archive"] = flattenArchive(archive)
+	}
+	r["env"] = flattenEnvs(toOpt(deployment.GetEnv()), regions)
 	r["ports"] = flattenPorts(toOpt(deployment.GetPorts()))
 	r["skip_cache"] = deployment.GetSkipCache()
 	if check, ok := deployment.GetHealthChecksOk(); ok {
 		r["health_checks"] = flattenHealthChecks(toOpt(check))
 	}
 	r["routes"] = flattenRoutes(toOpt(deployment.GetRoutes()))
-	r["instance_types"] = flattenInstanceTypes(toOpt(deployment.GetInstanceTypes()))
-	r["scalings"] = flattenScalings(toOpt(deployment.GetScalings()))
-	r["regions"] = flattenRegions(&deployment.Regions)
-	r["volumes"] = flattenVolumes(&deployment.Volumes)
+	r["instance_types"] = flattenInstanceTypes(toOpt(deployment.GetInstanceTypes()), regions)
+	r["scalings"] = flattenScalings(toOpt(deployment.GetScalings()), regions)
+	r["regions"] = flattenRegions(&regions)
+	r["volumes"] = flattenVolumes(toOpt(deployment.GetVolumes()), regions)
+	r["config_file"] = flattenConfigFiles(toOpt(deployment.GetFileMounts()))
 
 	result = append(result, r)
 
@@ -1392,27 +2071,290 @@ func resourceKoyebService() *schema.Resource {
 		DeleteContext: resourceKoyebServiceDelete,
 
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: resourceKoyebServiceImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+			Update: schema.DefaultTimeout(30 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
 		},
 
+		CustomizeDiff: customizeServiceDiff,
+
 		Schema: serviceSchema(),
 	}
 }
 
+// customizeServiceDiff refuses the replacement of protected services and
+// validates the rules of the deployment definition which involve several
+// attributes and can't be expressed by the schema.
+func customizeServiceDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if err := customizeDeletionProtectionDiff("service", serviceSchema())(ctx, d, meta); err != nil {
+		return err
+	}
+
+	for _, validate := range []func(*schema.ResourceDiff) error{
+		validateSource,
+		validateGitSource,
+		validateStrategy,
+		validatePorts,
+		validateScopes,
+		validateScalings,
+		validateVolumes,
+	} {
+		if err := validate(d); err != nil {
+			return err
+		}
+	}
+	if err := diffArchiveHash(d); err != nil {
+		return err
+	}
+	if d.Id() != "" && d.HasChange("definition.0.routes") {
+		if err := d.SetNewComputed("public_urls"); err != nil {
+			return err
+		}
+	}
+	if d.Id() != "" && (definitionChanged(d) || d.HasChanges("paused", "redeploy_triggers")) {
+		for _, key := range []string{"regional_deployments", "instances"} {
+			if err := d.SetNewComputed(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	if meta, ok := meta.(*providerMeta); ok && meta.validatePlans {
+		return validateServiceWithAPI(ctx, d, meta)
+	}
+	return nil
+}
+
+// definitionChanged returns whether an attribute of the definition changes.
+// The definition as a whole can differ from its prior state when none of its
+// attributes changes, because of the sets nested in other sets.
+func definitionChanged(d *schema.ResourceDiff) bool {
+	regions := expandRegions(d.Get("definition.0.regions").(*schema.Set).List())
+	for key, s := range deploymentDefinitionSchena().Schema {
+		if !d.HasChange("definition.0." + key) {
+			continue
+		}
+		// The diff of scopes written differently is suppressed, but
+		// ResourceDiff still reads the configured scopes
+		if scopesKey, ok := scopedDefinitionAttributes[key]; ok {
+			oldElements, newElements := d.GetChange("definition.0." + key)
+			if equivalentScopedElements(oldElements.(*schema.Set), newElements.(*schema.Set), s.Elem.(*schema.Resource), scopesKey, regions) {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// validateSource checks that exactly one of the docker, git and archive
+// sources is set.
+func validateSource(d *schema.ResourceDiff) error {
+	set := 0
+	for _, key := range []string{"docker", "git", "archive"} {
+		if !d.NewValueKnown("definition.0." + key) {
+			return nil
+		}
+		set += d.Get("definition.0." + key).(*schema.Set).Len()
+	}
+	if set != 1 {
+		return fmt.Errorf("definition.0: exactly one of docker, git and archive must be set")
+	}
+	return nil
+}
+
+// validateGitSource checks that exactly one of the branch, tag and commit SHA
+// of the git source is set.
+func validateGitSource(d *schema.ResourceDiff) error {
+	// The set is unknown as long as one of its values is unknown
+	if !d.NewValueKnown("definition.0.git") {
+		return nil
+	}
+
+	for _, rawGitSource := range d.Get("definition.0.git").(*schema.Set).List() {
+		gitSource := rawGitSource.(map[string]interface{})
+
+		set := 0
+		for _, key := range []string{"branch", "tag", "sha"} {
+			if gitSource[key].(string) != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("definition.0.git: exactly one of branch, tag and sha must be set")
+		}
+	}
+
+	return nil
+}
+
+// validateStrategy checks that the deployment strategy is supported by the
+// service type.
+func validateStrategy(d *schema.ResourceDiff) error {
+	if !d.NewValueKnown("definition.0.strategy") || !d.NewValueKnown("definition.0.type") {
+		return nil
+	}
+
+	for _, rawStrategy := range d.Get("definition.0.strategy").(*schema.Set).List() {
+		strategy := rawStrategy.(map[string]interface{})
+		if strategy["type"].(string) == "BLUE_GREEN" && d.Get("definition.0.type").(string) == "WORKER" {
+			return fmt.Errorf("definition.0.strategy: WORKER services can't be deployed with the BLUE_GREEN strategy")
+		}
+	}
+
+	return nil
+}
+
+// validateScalings checks the scale-to-zero settings of the scalings against
+// the service type and the instance types of their regions. Only web
+// services can scale to zero, and free instances, which always scale to zero
+// after a delay set by the platform, can't run more than one instance. Eco
+// instances neither scale to zero nor autoscale, so they run a fixed number of
+// instances. The bounds of sleep_idle_delay are left to the API.
+func validateScalings(d *schema.ResourceDiff) error {
+	for _, key := range []string{"definition.0.type", "definition.0.regions", "definition.0.instance_types", "definition.0.scalings"} {
+		if !d.NewValueKnown(key) {
+			return nil
+		}
+	}
+
+	regions := expandRegions(d.Get("definition.0.regions").(*schema.Set).List())
+	instanceTypes := map[string]string{}
+	for _, rawInstanceType := range d.Get("definition.0.instance_types").(*schema.Set).List() {
+		instanceType := rawInstanceType.(map[string]interface{})
+		for _, scope := range effectiveScopes(instanceType["scopes"].(*schema.Set).List(), regions) {
+			instanceTypes[scope] = instanceType["type"].(string)
+		}
+	}
+
+	for _, rawScaling := range d.Get("definition.0.scalings").(*schema.Set).List() {
+		scaling := rawScaling.(map[string]interface{})
+		min, max := scaling["min"].(int), scaling["max"].(int)
+
+		sleepIdleDelays := []int{}
+		for _, rawTarget := range scaling["targets"].(*schema.Set).List() {
+			for _, rawValue := range rawTarget.(map[string]interface{})["sleep_idle_delay"].(*schema.Set).List() {
+				sleepIdleDelays = append(sleepIdleDelays, rawValue.(map[string]interface{})["value"].(int))
+			}
+		}
+
+		free, eco := false, false
+		for _, scope := range effectiveScopes(scaling["scopes"].(*schema.Set).List(), regions) {
+			free = free || instanceTypes[scope] == "free"
+			eco = eco || strings.HasPrefix(instanceTypes[scope], "eco-")
+		}
+
+		switch {
+		case min > max:
+			return fmt.Errorf("definition.0.scalings: min can't be greater than max")
+		case min == 0 && d.Get("definition.0.type").(string) == "WORKER":
+			return fmt.Errorf("definition.0.scalings: WORKER services can't scale to zero")
+		case len(sleepIdleDelays) > 0 && min != 0:
+			return fmt.Errorf("definition.0.scalings: sleep_idle_delay requires min to be 0")
+		case free && max > 1:
+			return fmt.Errorf("definition.0.scalings: free instances can't scale beyond 1 instance")
+		case free && len(sleepIdleDelays) > 0:
+			return fmt.Errorf("definition.0.scalings: the sleep_idle_delay of free instances can't be changed")
+		case eco && min == 0:
+			return fmt.Errorf("definition.0.scalings: eco instances can't scale to zero")
+		case eco && min != max:
+			return fmt.Errorf("definition.0.scalings: eco instances can't autoscale, min and max must be equal")
+		}
+	}
+
+	return nil
+}
+
+// validateVolumes checks that services with volumes are deployed in a single
+// region with a single instance.
+func validateVolumes(d *schema.ResourceDiff) error {
+	for _, key := range []string{"definition.0.volumes", "definition.0.regions", "definition.0.scalings"} {
+		if !d.NewValueKnown(key) {
+			return nil
+		}
+	}
+
+	if d.Get("definition.0.volumes").(*schema.Set).Len() == 0 {
+		return nil
+	}
+	if d.Get("definition.0.regions").(*schema.Set).Len() != 1 {
+		return fmt.Errorf("definition.0.volumes: services with volumes must be deployed in a single region")
+	}
+	for _, rawScaling := range d.Get("definition.0.scalings").(*schema.Set).List() {
+		if rawScaling.(map[string]interface{})["max"].(int) != 1 {
+			return fmt.Errorf("definition.0.volumes: services with volumes can't scale beyond 1 instance")
+		}
+	}
+
+	return nil
+}
+
+// diffArchiveHash plans a new deployment when the content of the archive
+// source directory changed since it was last uploaded.
+func diffArchiveHash(d *schema.ResourceDiff) error {
+	if !d.NewValueKnown("definition.0.archive") {
+		return d.SetNewComputed("archive_hash")
+	}
+
+	var hash string
+	if definition := d.Get("definition").([]interface{}); len(definition) > 0 && definition[0] != nil {
+		if path, ignorePatterns, ok := archiveSourceConfig(definition[0].(map[string]interface{})); ok {
+			var err error
+			if hash, err = hashArchive(path, ignorePatterns); err != nil {
+				return fmt.Errorf("definition.0.archive: %w", err)
+			}
+		}
+	}
+
+	if hash != d.Get("archive_hash").(string) {
+		return d.SetNew("archive_hash", hash)
+	}
+	return nil
+}
+
+func resourceKoyebServiceImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	// Attributes which are not returned by the API are set to their default
+	// value to avoid a diff right after the import
+	d.Set("wait_for_deployment", true)
+	d.Set("deletion_protection", false)
+	// The hash of the archive of an imported service is unknown until the
+	// next apply, which records it
+	d.Set("archive_hash", "")
+
+	return []*schema.ResourceData{d}, nil
+}
+
 func setServiceAttribute(
 	d *schema.ResourceData,
 	service *koyeb.Service,
-	// latestDeployment *koyeb.Deployment,
+	latestDeployment *koyeb.Deployment,
 ) error {
 	d.SetId(service.GetId())
 	d.Set("name", service.GetName())
 	d.Set("app_id", service.GetAppId())
-	// d.Set("definition", flattenDeploymentDefinition(toOpt(latestDeployment.GetDefinition())))
+	definition := flattenDeploymentDefinition(toOpt(latestDeployment.GetDefinition()))
+	prior := d.Get("definition").([]interface{})
+	// The path and the ignore patterns of the archive source are not sent to
+	// the API, so they are kept from the prior state. They are empty after an
+	// import.
+	if archive, ok := definition[0].(map[string]interface{})["archive"].([]interface{}); ok && len(prior) > 0 && prior[0] != nil {
+		if path, ignorePatterns, ok := archiveSourceConfig(prior[0].(map[string]interface{})); ok {
+			archive[0].(map[string]interface{})["path"] = path
+			archive[0].(map[string]interface{})["ignore_patterns"] = ignorePatterns
+		}
+	}
+	d.Set("definition", definition)
 	d.Set("organization_id", service.GetOrganizationId())
 	d.Set("active_deployment", service.GetActiveDeploymentId())
 	d.Set("latest_deployment", service.GetLatestDeploymentId())
+	d.Set("git_sha", latestDeployment.ProvisioningInfo.GetSha())
 	d.Set("version", service.GetVersion())
 	d.Set("status", service.GetStatus())
+	d.Set("paused", service.GetStatus() == koyeb.SERVICESTATUS_PAUSED || service.GetStatus() == koyeb.SERVICESTATUS_PAUSING)
 	d.Set("messages", strings.Join(service.GetMessages(), " "))
 	d.Set("paused_at", service.GetPausedAt().UTC().String())
 	d.Set("resumed_at", service.GetResumedAt().UTC().String())
@@ -1424,13 +2366,12 @@ func setServiceAttribute(
 }
 
 func resourceKoyebServiceCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
-	mapper := idmapper.NewMapper(context.Background(), client)
-	appMapper := mapper.App()
+	client := meta.(*providerMeta).client
+	resolver := meta.(*providerMeta).resolver
 	var appId string
 
 	if d.Get("app_name").(string) != "" {
-		id, err := appMapper.ResolveID(d.Get("app_name").(string))
+		id, err := resolver.ResolveID(ctx, appKind, d.Get("app_name").(string))
 		if err != nil {
 			return diag.Errorf("Error creating service: %s", err)
 		}
@@ -1439,29 +2380,45 @@ func resourceKoyebServiceCreate(ctx context.Context, d *schema.ResourceData, met
 	}
 
 	definition := expandDeploymentDefinition(d.Get("definition").([]interface{})[0].(map[string]interface{}))
+	if diags := uploadArchiveSource(ctx, client, d, definition); diags.HasError() {
+		return diags
+	}
 
-	res, resp, err := client.ServicesApi.CreateService(context.Background()).Service(koyeb.CreateService{
+	res, _, err := client.ServicesApi.CreateService(ctx).Service(koyeb.CreateService{
 		AppId:      &appId,
 		Definition: definition,
 	}).Execute()
 	if err != nil {
-		return diag.Errorf("Error creating service: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error creating service", err, resourceKoyebService().Schema)
 	}
 
 	d.SetId(*res.Service.Id)
+	meta.(*providerMeta).resolver.Invalidate(serviceKind)
 	log.Printf("[INFO] Created service name: %s", *res.Service.Name)
 
+	if d.Get("wait_for_deployment").(bool) {
+		err := waitForDeployment(ctx, client, res.Service.GetLatestDeploymentId(), d.Timeout(schema.TimeoutCreate))
+		if err != nil {
+			return diag.Errorf("Error waiting for service deployment: %s", err)
+		}
+	}
+
+	if d.Get("paused").(bool) {
+		if diags := pauseService(ctx, client, d.Id(), d.Timeout(schema.TimeoutCreate)); diags.HasError() {
+			return diags
+		}
+	}
+
 	return resourceKoyebServiceRead(ctx, d, meta)
 }
 
 func resourceKoyebServiceRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
-	mapper := idmapper.NewMapper(context.Background(), client)
-	serviceMapper := mapper.Service()
+	client := meta.(*providerMeta).client
+	resolver := meta.(*providerMeta).resolver
 	var serviceId string
 
 	if d.Id() != "" {
-		id, err := serviceMapper.ResolveID(d.Id())
+		id, err := resolver.ResolveID(ctx, serviceKind, d.Id())
 		if err != nil {
 			return diag.Errorf("Error retrieving service: %s", err)
 		}
@@ -1469,51 +2426,347 @@ func resourceKoyebServiceRead(ctx context.Context, d *schema.ResourceData, meta
 		serviceId = id
 	}
 
-	serviceRes, resp, err := client.ServicesApi.GetService(context.Background(), serviceId).Execute()
+	serviceRes, resp, err := client.ServicesApi.GetService(ctx, serviceId).Execute()
 	if err != nil {
 		// If the service is somehow already destroyed, mark as
 		// successfully gone
-		if resp.StatusCode == 404 {
+		if isNotFound(resp) {
 			d.SetId("")
 			return nil
 		}
 
-		return diag.Errorf("Error retrieving service: %s (%v %v)", err, resp, serviceRes)
+		return apiErrorDiagnostics("Error retrieving service", err, resourceKoyebService().Schema)
 	}
 
-	// deploymentRes, resp, err := client.DeploymentsApi.GetDeployment(context.Background(), *serviceRes.Service.LatestDeploymentId).Execute()
-	// if err != nil {
-	// 	return diag.Errorf("Error retrieving service latest deployment: %s (%v %v", err, resp, serviceRes)
-	// }
+	deploymentRes, resp, err := client.DeploymentsApi.GetDeployment(ctx, serviceRes.Service.GetLatestDeploymentId()).Execute()
+	if err != nil {
+		return apiErrorDiagnostics("Error retrieving service latest deployment", err, resourceKoyebService().Schema)
+	}
 
-	setServiceAttribute(d, serviceRes.Service)
+	setServiceAttribute(d, serviceRes.Service, deploymentRes.Deployment)
+
+	appRes, _, err := client.AppsApi.GetApp(ctx, serviceRes.Service.GetAppId()).Execute()
+	if err != nil {
+		return apiErrorDiagnostics("Error retrieving service app", err, resourceKoyebService().Schema)
+	}
+
+	// The app name is only unknown after an import. The service data source
+	// has no app name.
+	if appName, ok := d.Get("app_name").(string); ok && appName == "" {
+		d.Set("app_name", appRes.App.GetName())
+	}
+
+	// The public URLs are served by the active deployment, the latest
+	// deployment may not be healthy yet
+	routingDeployment := deploymentRes.Deployment
+	if activeDeploymentId := serviceRes.Service.GetActiveDeploymentId(); activeDeploymentId != "" && activeDeploymentId != routingDeployment.GetId() {
+		activeDeploymentRes, _, err := client.DeploymentsApi.GetDeployment(ctx, activeDeploymentId).Execute()
+		if err != nil {
+			return apiErrorDiagnostics("Error retrieving service active deployment", err, resourceKoyebService().Schema)
+		}
+		routingDeployment = activeDeploymentRes.Deployment
+	}
+
+	setServiceRouting(d, serviceRes.Service, appRes.App, routingDeployment)
+
+	return setServiceInstances(ctx, client, d, serviceRes.Service.GetActiveDeploymentId())
+}
+
+// setServiceRouting sets the public URLs of the service, built from the
+// domains of its app and the paths of the routes of deployment, and its
+// private hostname. deployment is the active deployment of the service, or its
+// latest deployment when none is active yet.
+func setServiceRouting(
+	d *schema.ResourceData,
+	service *koyeb.Service,
+	app *koyeb.App,
+	deployment *koyeb.Deployment,
+) {
+	routes := append([]koyeb.DeploymentRoute{}, deployment.GetDefinition().Routes...)
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].GetPath() < routes[j].GetPath()
+	})
+
+	var appDomain string
+	publicURLs := []string{}
+	for _, domain := range app.Domains {
+		if domain.GetType() == koyeb.DOMAINTYPE_AUTOASSIGNED {
+			appDomain = domain.GetName()
+		}
+		for _, route := range routes {
+			publicURLs = append(publicURLs, "https://"+domain.GetName()+route.GetPath())
+		}
+	}
+
+	d.Set("app_domain", appDomain)
+	d.Set("public_urls", publicURLs)
+	d.Set("private_hostname", servicePrivateHostname(service.GetName(), app.GetName()))
+}
+
+// servicePrivateHostname returns the hostname of a service on the private
+// network of its organization. It isn't exposed by the API, so it is derived
+// from the names of the service and of its app.
+func servicePrivateHostname(serviceName string, appName string) string {
+	return fmt.Sprintf("%s.%s.koyeb", serviceName, appName)
+}
+
+// setServiceInstances sets the regional deployments and the instances of the
+// active deployment of the service.
+func setServiceInstances(ctx context.Context, client *koyeb.APIClient, d *schema.ResourceData, activeDeploymentId string) diag.Diagnostics {
+	regionalDeployments := []koyeb.RegionalDeploymentListItem{}
+	instances := []koyeb.InstanceListItem{}
+
+	if activeDeploymentId != "" {
+		err := listPages(func(limit string, offset int) (int, bool, error) {
+			res, _, err := client.RegionalDeploymentsApi.ListRegionalDeployments(ctx).DeploymentId(activeDeploymentId).Limit(limit).Offset(strconv.Itoa(offset)).Execute()
+			if err != nil {
+				return 0, false, err
+			}
+			regionalDeployments = append(regionalDeployments, res.GetRegionalDeployments()...)
+			return len(res.GetRegionalDeployments()), res.GetHasNext(), nil
+		})
+		if err != nil {
+			return apiErrorDiagnostics("Error retrieving service regional deployments", err, resourceKoyebService().Schema)
+		}
+
+		err = listPages(func(limit string, offset int) (int, bool, error) {
+			res, _, err := client.InstancesApi.ListInstances(ctx).DeploymentId(activeDeploymentId).Limit(limit).Offset(strconv.Itoa(offset)).Execute()
+			if err != nil {
+				return 0, false, err
+			}
+			instances = append(instances, res.GetInstances()...)
+			return len(res.GetInstances()), int64(offset+len(res.GetInstances())) < res.GetCount(), nil
+		})
+		if err != nil {
+			return apiErrorDiagnostics("Error retrieving service instances", err, resourceKoyebService().Schema)
+		}
+	}
+
+	current := flattenInstances(instances)
+	d.Set("instances", current)
+	d.Set("regional_deployments", flattenRegionalDeployments(regionalDeployments, current))
 
 	return nil
 }
 
+// flattenInstances returns the instances which are not stopped, sorted by
+// region. Instances replacing a failed instance keep its replica index in
+// the regional deployment, so the restarts of an instance are the failed
+// instances with the same replica index.
+func flattenInstances(instances []koyeb.InstanceListItem) []map[string]interface{} {
+	sort.SliceStable(instances, func(i, j int) bool {
+		return instances[i].GetCreatedAt().Before(instances[j].GetCreatedAt())
+	})
+
+	restarts := map[string]int{}
+	result := []map[string]interface{}{}
+	for _, instance := range instances {
+		replica := fmt.Sprintf("%s/%d", instance.GetRegionalDeploymentId(), instance.GetReplicaIndex())
+
+		switch instance.GetStatus() {
+		case koyeb.INSTANCESTATUS_ERROR:
+			restarts[replica]++
+		case koyeb.INSTANCESTATUS_STOPPING, koyeb.INSTANCESTATUS_STOPPED:
+		default:
+			result = append(result, map[string]interface{}{
+				"id":                     instance.GetId(),
+				"region":                 instance.GetRegion(),
+				"regional_deployment_id": instance.GetRegionalDeploymentId(),
+				"status":                 string(instance.GetStatus()),
+				"allocated_at":           instance.GetCreatedAt().UTC().String(),
+				"restart_count":          restarts[replica],
+			})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i]["region"].(string) < result[j]["region"].(string)
+	})
+	return result
+}
+
+func flattenRegionalDeployments(regionalDeployments []koyeb.RegionalDeploymentListItem, instances []map[string]interface{}) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for _, regionalDeployment := range regionalDeployments {
+		healthy := 0
+		for _, instance := range instances {
+			if instance["regional_deployment_id"] == regionalDeployment.GetId() && instance["status"] == string(koyeb.INSTANCESTATUS_HEALTHY) {
+				healthy++
+			}
+		}
+
+		result = append(result, map[string]interface{}{
+			"id":                regionalDeployment.GetId(),
+			"region":            regionalDeployment.GetRegion(),
+			"status":            string(regionalDeployment.GetStatus()),
+			"messages":          strings.Join(regionalDeployment.GetMessages(), " "),
+			"healthy_instances": healthy,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i]["region"].(string) < result[j]["region"].(string)
+	})
+	return result
+}
+
 func resourceKoyebServiceUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	client := meta.(*providerMeta).client
+	paused := d.Get("paused").(bool)
 
-	definition := expandDeploymentDefinition(d.Get("definition").([]interface{})[0].(map[string]interface{}))
-	res, resp, err := client.ServicesApi.UpdateService(context.Background(), d.Id()).Service(koyeb.UpdateService{
-		Definition: definition,
-	}).Execute()
-	if err != nil {
-		return diag.Errorf("Error updating service: %s (%v %v)", err, resp, res)
+	if d.HasChange("paused") && !paused {
+		if diags := resumeService(ctx, client, d.Id(), d.Timeout(schema.TimeoutUpdate)); diags.HasError() {
+			return diags
+		}
+	}
+
+	var deploymentId string
+
+	// The hash of the archive of an imported service is unknown, so it is
+	// only recorded the first time it is planned.
+	oldArchiveHash, _ := d.GetChange("archive_hash")
+	if deployedDefinitionChanged(d) || (d.HasChange("archive_hash") && oldArchiveHash.(string) != "") {
+		definition := expandDeploymentDefinition(d.Get("definition").([]interface{})[0].(map[string]interface{}))
+		if diags := uploadArchiveSource(ctx, client, d, definition); diags.HasError() {
+			return diags
+		}
+
+		res, _, err := client.ServicesApi.UpdateService(ctx, d.Id()).Service(koyeb.UpdateService{
+			Definition: definition,
+		}).Execute()
+		if err != nil {
+			return apiErrorDiagnostics("Error updating service", err, resourceKoyebService().Schema)
+		}
+
+		log.Printf("[INFO] Updated service name: %s", *res.Service.Name)
+		deploymentId = res.Service.GetLatestDeploymentId()
+	} else if d.HasChange("redeploy_triggers") {
+		// Updating the definition already creates a new deployment, so the
+		// service is only redeployed when the definition is unchanged
+		res, _, err := client.ServicesApi.ReDeploy(ctx, d.Id()).Info(expandRedeployOptions(d.Get("redeploy_options").([]interface{}))).Execute()
+		if err != nil {
+			return apiErrorDiagnostics("Error redeploying service", err, resourceKoyebService().Schema)
+		}
+
+		log.Printf("[INFO] Redeployed service id: %s", d.Id())
+		deploymentId = res.Deployment.GetId()
+	}
+
+	// The deployment of a paused service does not start until the service is
+	// resumed
+	if deploymentId != "" && d.Get("wait_for_deployment").(bool) && !paused {
+		err := waitForDeployment(ctx, client, deploymentId, d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			return diag.Errorf("Error waiting for service deployment: %s", err)
+		}
 	}
 
-	log.Printf("[INFO] Updated service name: %s", *res.Service.Name)
+	if paused {
+		if diags := pauseService(ctx, client, d.Id(), d.Timeout(schema.TimeoutUpdate)); diags.HasError() {
+			return diags
+		}
+	}
 
 	return resourceKoyebServiceRead(ctx, d, meta)
+}
 
+// deployedDefinitionChanged returns whether the definition sent to the API
+// changed, ignoring the attributes which are only stored in the state such as
+// the path of the archive source.
+func deployedDefinitionChanged(d *schema.ResourceData) bool {
+	if !d.HasChange("definition") {
+		return false
+	}
+
+	oldDefinition, newDefinition := d.GetChange("definition")
+	return !reflect.DeepEqual(
+		expandDeploymentDefinition(oldDefinition.([]interface{})[0].(map[string]interface{})),
+		expandDeploymentDefinition(newDefinition.([]interface{})[0].(map[string]interface{})),
+	)
+}
+
+// uploadArchiveSource uploads the archive source directory of the definition,
+// if any, and sets the ID of the uploaded archive in the definition. Every new
+// definition references a new archive, even when the content is unchanged.
+func uploadArchiveSource(ctx context.Context, client *koyeb.APIClient, d *schema.ResourceData, definition *koyeb.DeploymentDefinition) diag.Diagnostics {
+	if definition.Archive == nil {
+		d.Set("archive_hash", "")
+		return nil
+	}
+
+	path, ignorePatterns, _ := archiveSourceConfig(d.Get("definition").([]interface{})[0].(map[string]interface{}))
+	id, hash, err := uploadArchive(ctx, client, path, ignorePatterns)
+	if err != nil {
+		return apiErrorDiagnostics("Error uploading archive", err, resourceKoyebService().Schema)
+	}
+	log.Printf("[INFO] Uploaded archive id: %s", id)
+
+	definition.Archive.Id = toOpt(id)
+	d.Set("archive_hash", hash)
+	return nil
+}
+
+// pauseService pauses the service, unless it is already paused, and waits for
+// it to be paused.
+func pauseService(ctx context.Context, client *koyeb.APIClient, serviceId string, timeout time.Duration) diag.Diagnostics {
+	res, _, err := client.ServicesApi.GetService(ctx, serviceId).Execute()
+	if err != nil {
+		return apiErrorDiagnostics("Error retrieving service", err, resourceKoyebService().Schema)
+	}
+
+	switch res.Service.GetStatus() {
+	case koyeb.SERVICESTATUS_PAUSED:
+		return nil
+	case koyeb.SERVICESTATUS_PAUSING:
+	default:
+		if _, _, err := client.ServicesApi.PauseService(ctx, serviceId).Execute(); err != nil {
+			return apiErrorDiagnostics("Error pausing service", err, resourceKoyebService().Schema)
+		}
+		log.Printf("[INFO] Paused service name: %s", res.Service.GetName())
+	}
+
+	if err := waitForServicePaused(ctx, client, serviceId, timeout); err != nil {
+		return diag.Errorf("Error waiting for service to be paused: %s", err)
+	}
+	return nil
+}
+
+// resumeService resumes the service and waits for it to be healthy.
+func resumeService(ctx context.Context, client *koyeb.APIClient, serviceId string, timeout time.Duration) diag.Diagnostics {
+	if _, _, err := client.ServicesApi.ResumeService(ctx, serviceId).Execute(); err != nil {
+		return apiErrorDiagnostics("Error resuming service", err, resourceKoyebService().Schema)
+	}
+	log.Printf("[INFO] Resumed service id: %s", serviceId)
+
+	if err := waitForServiceResumed(ctx, client, serviceId, timeout); err != nil {
+		return diag.Errorf("Error waiting for service to be resumed: %s", err)
+	}
+	return nil
 }
 
 func resourceKoyebServiceDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	if diags := checkDeletionProtection(d, "service"); diags.HasError() {
+		return diags
+	}
+
+	client := meta.(*providerMeta).client
+
+	_, _, err := client.ServicesApi.DeleteService(ctx, d.Id()).Execute()
+	if err != nil {
+		return apiErrorDiagnostics("Error deleting service", err, resourceKoyebService().Schema)
+	}
+
+	meta.(*providerMeta).resolver.Invalidate(serviceKind)
 
-	res, resp, err := client.ServicesApi.DeleteService(context.Background(), d.Id()).Execute()
+	err = (&statusWaiter{
+		Name:             fmt.Sprintf("service %s", d.Id()),
+		Refresh:          serviceStatusFunc(client, d.Id()),
+		Target:           []string{string(koyeb.SERVICESTATUS_DELETED)},
+		NotFoundIsTarget: true,
+		Timeout:          d.Timeout(schema.TimeoutDelete),
+	}).Wait(ctx)
 	if err != nil {
-		return diag.Errorf("Error deleting service: %s (%v %v)", err, resp, res)
+		return diag.Errorf("Error waiting for service deletion: %s", err)
 	}
 
 	d.SetId("")
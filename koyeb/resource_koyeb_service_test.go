@@ -2,14 +2,20 @@ package koyeb
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+	"golang.org/x/exp/slices"
 )
 
 func init() {
@@ -117,94 +123,1605 @@ func TestAccKoyebService_Basic(t *testing.T) {
 	})
 }
 
+func TestAccKoyebService_Paused(t *testing.T) {
+	var service koyeb.Service
+	appName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_paused, appName, appName, true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "paused", "true"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "status", "PAUSED"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_paused, appName, appName, false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "paused", "false"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "status", "HEALTHY"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_paused, appName, appName, true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "paused", "true"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "status", "PAUSED"),
+				),
+			},
+			{
+				// A service resumed outside of Terraform shows up as drift
+				PreConfig: func() {
+					client := testAccProvider.Meta().(*providerMeta).client
+					if _, _, err := client.ServicesApi.ResumeService(context.Background(), service.GetId()).Execute(); err != nil {
+						t.Fatalf("unable to resume the service: %s", err)
+					}
+				},
+				Config:             fmt.Sprintf(testAccCheckKoyebServiceConfig_paused, appName, appName, true),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestAccKoyebService_Import(t *testing.T) {
+	var service koyeb.Service
+	appName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_single_region_scopes, appName, appName),
+				Check:  testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+			},
+			{
+				ResourceName:       "koyeb_service.bar",
+				ImportState:        true,
+				ImportStateVerify:  true,
+				ImportStatePersist: true,
+			},
+			{
+				// The scopes read from the API cover all the regions of the
+				// service, which is equivalent to the configured ones
+				Config:   fmt.Sprintf(testAccCheckKoyebServiceConfig_single_region_scopes, appName, appName),
+				PlanOnly: true,
+			},
+			{
+				// A definition changed outside of Terraform shows up as drift
+				PreConfig: func() {
+					client := testAccProvider.Meta().(*providerMeta).client
+					res, _, err := client.DeploymentsApi.GetDeployment(context.Background(), service.GetLatestDeploymentId()).Execute()
+					if err != nil {
+						t.Fatalf("unable to retrieve the deployment: %s", err)
+					}
+					definition := res.Deployment.GetDefinition()
+					definition.Docker.Image = toOpt("koyeb/demo:latest")
+					if _, _, err := client.ServicesApi.UpdateService(context.Background(), service.GetId()).Service(koyeb.UpdateService{
+						Definition: &definition,
+					}).Execute(); err != nil {
+						t.Fatalf("unable to update the service: %s", err)
+					}
+				},
+				Config:             fmt.Sprintf(testAccCheckKoyebServiceConfig_single_region_scopes, appName, appName),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestFlattenDeploymentDefinition_RoundTrip(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, serviceSchema(), map[string]interface{}{
+		"app_name": "app",
+		"definition": []interface{}{
+			map[string]interface{}{
+				"name": "service",
+				"instance_types": []interface{}{
+					map[string]interface{}{"type": "micro"},
+				},
+				"ports": []interface{}{
+					map[string]interface{}{"port": 3000, "protocol": "http"},
+				},
+				"scalings": []interface{}{
+					map[string]interface{}{
+						"min": 1,
+						"max": 3,
+						"targets": []interface{}{
+							map[string]interface{}{
+								"average_cpu": []interface{}{
+									map[string]interface{}{"value": 80},
+								},
+							},
+						},
+					},
+				},
+				"env": []interface{}{
+					map[string]interface{}{"key": "FOO", "value": "BAR"},
+					map[string]interface{}{"key": "FRA_ONLY", "value": "1", "scopes": []interface{}{"region:fra"}},
+				},
+				"routes": []interface{}{
+					map[string]interface{}{"path": "/", "port": 3000},
+				},
+				"health_checks": []interface{}{
+					map[string]interface{}{
+						"http": []interface{}{
+							map[string]interface{}{"path": "/health", "port": 3000},
+						},
+					},
+				},
+				"regions": []interface{}{"fra", "was"},
+				"strategy": []interface{}{
+					map[string]interface{}{"type": "BLUE_GREEN"},
+				},
+				"config_file": []interface{}{
+					map[string]interface{}{"path": "/etc/nginx/nginx.conf", "content": "worker_processes 1;\n"},
+					map[string]interface{}{"path": "/app/run.sh", "content": "#!/bin/sh\n", "permissions": "0755"},
+				},
+				"docker": []interface{}{
+					map[string]interface{}{"image": "koyeb/demo"},
+				},
+			},
+		},
+	})
+
+	definition := expandDeploymentDefinition(d.Get("definition").([]interface{})[0].(map[string]interface{}))
+	expected, err := json.Marshal(definition)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The API returns the scopes of global entries expanded to all the
+	// regions of the deployment.
+	for i := range definition.Env {
+		if len(definition.Env[i].Scopes) == 0 {
+			definition.Env[i].Scopes = []string{"region:was", "region:fra"}
+		}
+	}
+	definition.InstanceTypes[0].Scopes = []string{"region:fra", "region:was"}
+	definition.Scalings[0].Scopes = []string{"region:fra", "region:was"}
+
+	if err := d.Set("definition", flattenDeploymentDefinition(definition)); err != nil {
+		t.Fatalf("error setting definition: %s", err)
+	}
+
+	got, err := json.Marshal(expandDeploymentDefinition(d.Get("definition").([]interface{})[0].(map[string]interface{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expectedMap, gotMap map[string]interface{}
+	json.Unmarshal(expected, &expectedMap)
+	json.Unmarshal(got, &gotMap)
+
+	if !reflect.DeepEqual(expectedMap, gotMap) {
+		t.Fatalf("definition did not round-trip:\nexpected: %s\ngot:      %s", expected, got)
+	}
+}
+
+func TestFlattenScopes(t *testing.T) {
+	cases := []struct {
+		scopes   []string
+		regions  []string
+		expected []string
+	}{
+		{[]string{"region:fra", "region:was"}, []string{"was", "fra"}, []string{}},
+		{[]string{"region:fra"}, []string{"fra", "was"}, []string{"region:fra"}},
+		{[]string{"region:fra"}, []string{"was"}, []string{"region:fra"}},
+		{nil, []string{"fra"}, nil},
+	}
+
+	for _, c := range cases {
+		if got := flattenScopes(c.scopes, c.regions); !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("flattenScopes(%v, %v) = %v, expected %v", c.scopes, c.regions, got, c.expected)
+		}
+	}
+}
+
+func TestAccKoyebService_RedeployTriggers(t *testing.T) {
+	var service koyeb.Service
+	var first, second koyeb.Deployment
+	appName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_redeploy_triggers, appName, appName, "v1"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					testAccCheckKoyebServiceLatestDeployment(&service, &first),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "redeploy_triggers.image", "v1"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_redeploy_triggers, appName, appName, "v2"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					testAccCheckKoyebServiceLatestDeployment(&service, &second),
+					func(*terraform.State) error {
+						if second.GetId() == first.GetId() {
+							return fmt.Errorf("Service not redeployed when its triggers changed")
+						}
+						if second.GetStatus() != koyeb.DEPLOYMENTSTATUS_HEALTHY || second.Definition.Docker.GetImage() != "koyeb/demo" {
+							return fmt.Errorf("Unexpected redeployment %s of %v", second.GetStatus(), second.Definition)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestExpandRedeployOptions(t *testing.T) {
+	info := expandRedeployOptions(nil)
+	if info.GetUseCache() || info.Sha != nil {
+		t.Fatalf("expected a redeployment without cache by default, got %+v", info)
+	}
+
+	info = expandRedeployOptions([]interface{}{
+		map[string]interface{}{"use_cache": true, "sha": "2f2a1c1"},
+	})
+	if !info.GetUseCache() || info.GetSha() != "2f2a1c1" {
+		t.Fatalf("unexpected redeploy options %+v", info)
+	}
+}
+
+func TestValidateGitSource(t *testing.T) {
+	cases := []struct {
+		git   map[string]interface{}
+		valid bool
+	}{
+		{map[string]interface{}{"branch": "main"}, true},
+		{map[string]interface{}{"tag": "v1.0.0"}, true},
+		{map[string]interface{}{"sha": "2f2a1c1"}, true},
+		{map[string]interface{}{"branch": "main", "tag": "v1.0.0"}, false},
+		{map[string]interface{}{"tag": "v1.0.0", "sha": "2f2a1c1"}, false},
+		{map[string]interface{}{}, false},
+	}
+
+	for _, c := range cases {
+		git := map[string]interface{}{
+			"repository": "github.com/koyeb/example-flask",
+			"buildpack":  []interface{}{map[string]interface{}{}},
+		}
+		for k, v := range c.git {
+			git[k] = v
+		}
+		config := terraform.NewResourceConfigRaw(map[string]interface{}{
+			"app_name": "app",
+			"definition": []interface{}{
+				map[string]interface{}{
+					"name":           "service",
+					"regions":        []interface{}{"fra"},
+					"instance_types": []interface{}{map[string]interface{}{"type": "nano"}},
+					"scalings":       []interface{}{map[string]interface{}{"min": 1, "max": 1}},
+					"git":            []interface{}{git},
+				},
+			},
+		})
+
+		_, err := resourceKoyebService().Diff(context.Background(), nil, config, nil)
+		if c.valid && err != nil {
+			t.Errorf("expected %v to be valid, got %s", c.git, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("expected %v to be rejected", c.git)
+		}
+	}
+}
+
+func TestAccKoyebService_GitSha(t *testing.T) {
+	var service koyeb.Service
+	appName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_git_ref, appName, appName, `tag = "v1.0.0"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "definition.0.git.#", "1"),
+					resource.TestMatchResourceAttr("koyeb_service.bar", "git_sha", regexp.MustCompile(`^[0-9a-f]{7,40}$`)),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_git_ref, appName, appName, `sha = "2f2a1c1"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					resource.TestMatchResourceAttr("koyeb_service.bar", "git_sha", regexp.MustCompile(`^2f2a1c1`)),
+				),
+			},
+		},
+	})
+}
+
+func TestValidateStrategy(t *testing.T) {
+	cases := []struct {
+		serviceType string
+		strategy    string
+		valid       bool
+	}{
+		{"WEB", "BLUE_GREEN", true},
+		{"WEB", "CANARY", true},
+		{"WORKER", "IMMEDIATE", true},
+		{"WORKER", "ROLLING", true},
+		{"WORKER", "BLUE_GREEN", false},
+	}
+
+	for _, c := range cases {
+		config := terraform.NewResourceConfigRaw(map[string]interface{}{
+			"app_name": "app",
+			"definition": []interface{}{
+				map[string]interface{}{
+					"name":           "service",
+					"type":           c.serviceType,
+					"regions":        []interface{}{"fra"},
+					"instance_types": []interface{}{map[string]interface{}{"type": "nano"}},
+					"scalings":       []interface{}{map[string]interface{}{"min": 1, "max": 1}},
+					"docker":         []interface{}{map[string]interface{}{"image": "koyeb/demo"}},
+					"strategy":       []interface{}{map[string]interface{}{"type": c.strategy}},
+				},
+			},
+		})
+
+		_, err := resourceKoyebService().Diff(context.Background(), nil, config, nil)
+		if c.valid && err != nil {
+			t.Errorf("expected %s to be valid for %s services, got %s", c.strategy, c.serviceType, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("expected %s to be rejected for %s services", c.strategy, c.serviceType)
+		}
+	}
+}
+
+func TestAccKoyebService_Strategy(t *testing.T) {
+	var service koyeb.Service
+	var deployment koyeb.Deployment
+	appName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				// The platform default is read when no strategy is set
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_strategy, appName, appName, "WEB", ""),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "definition.0.strategy.#", "1"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_strategy, appName, appName, "WORKER", `strategy {
+		  type = "IMMEDIATE"
+		}`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					testAccCheckKoyebServiceLatestDeployment(&service, &deployment),
+					func(*terraform.State) error {
+						if deployment.Definition.Strategy.GetType() != koyeb.DEPLOYMENTSTRATEGYTYPE_IMMEDIATE {
+							return fmt.Errorf("Service not deployed with the immediate strategy: %v", deployment.Definition.Strategy)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccKoyebService_ConfigFiles(t *testing.T) {
+	var service koyeb.Service
+	var deployment koyeb.Deployment
+	appName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_config_file, appName, appName, `["10.0.0.1:9100"]`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "definition.0.config_file.#", "1"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_config_file, appName, appName, `["10.0.0.1:9100", "10.0.0.2:9100"]`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					testAccCheckKoyebServiceLatestDeployment(&service, &deployment),
+					func(*terraform.State) error {
+						if len(deployment.Definition.FileMounts) != 1 {
+							return fmt.Errorf("Expected one file to be mounted, got %v", deployment.Definition.FileMounts)
+						}
+						file := deployment.Definition.FileMounts[0]
+						if file.Raw.GetContent() != `["10.0.0.1:9100", "10.0.0.2:9100"]` || file.GetPermissions() != "0644" {
+							return fmt.Errorf("Updated file not deployed, got %s %s", file.Raw.GetContent(), file.GetPermissions())
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccKoyebService_Archive(t *testing.T) {
+	var service koyeb.Service
+	var first, second, imported koyeb.Deployment
+	appName := randomTestName()
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"main.go": "package main"})
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_archive, appName, appName, dir),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					testAccCheckKoyebServiceLatestDeployment(&service, &first),
+					resource.TestCheckResourceAttrSet("koyeb_service.bar", "archive_hash"),
+					func(*terraform.State) error {
+						if first.Definition.Archive.GetId() == "" {
+							return fmt.Errorf("Service not deployed from an archive: %v", first.Definition)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// Changes of ignored files don't trigger a new deployment
+				PreConfig: func() {
+					writeTestFiles(t, dir, map[string]string{"debug.log": "debug"})
+				},
+				Config:   fmt.Sprintf(testAccCheckKoyebServiceConfig_archive, appName, appName, dir),
+				PlanOnly: true,
+			},
+			{
+				PreConfig: func() {
+					writeTestFiles(t, dir, map[string]string{"main.go": "package main\n"})
+				},
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_archive, appName, appName, dir),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					testAccCheckKoyebServiceLatestDeployment(&service, &second),
+					func(*terraform.State) error {
+						if second.GetId() == first.GetId() || second.Definition.Archive.GetId() == first.Definition.Archive.GetId() {
+							return fmt.Errorf("Service not redeployed with a new archive when the directory changed")
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// The path and the ignore patterns are not stored by the API
+				ResourceName:            "koyeb_service.bar",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"definition.0.archive", "archive_hash"},
+				ImportStatePersist:      true,
+			},
+			{
+				// The first apply after the import records the path and the
+				// hash of the directory without uploading it again
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_archive, appName, appName, dir),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					testAccCheckKoyebServiceLatestDeployment(&service, &imported),
+					resource.TestCheckResourceAttrSet("koyeb_service.bar", "archive_hash"),
+					func(*terraform.State) error {
+						if imported.GetId() != second.GetId() {
+							return fmt.Errorf("Service redeployed after its import")
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestValidateScopes(t *testing.T) {
+	cases := []struct {
+		name   string
+		update func(definition map[string]interface{})
+		valid  bool
+	}{
+		{"disjoint scopes", func(definition map[string]interface{}) {}, true},
+		{"env defined twice", func(definition map[string]interface{}) {
+			definition["env"] = append(definition["env"].([]interface{}),
+				map[string]interface{}{"key": "DATABASE_URL", "value": "postgres://all"})
+		}, false},
+		{"env scopes written differently", func(definition map[string]interface{}) {
+			definition["env"] = append(definition["env"].([]interface{}),
+				map[string]interface{}{"key": "LOG_LEVEL", "value": "debug", "scopes": []interface{}{"fra"}})
+		}, false},
+		{"instance types overlapping", func(definition map[string]interface{}) {
+			definition["instance_types"] = append(definition["instance_types"].([]interface{}),
+				map[string]interface{}{"type": "medium"})
+		}, false},
+		{"scalings overlapping", func(definition map[string]interface{}) {
+			definition["scalings"] = append(definition["scalings"].([]interface{}),
+				map[string]interface{}{"min": 1, "max": 2, "scopes": []interface{}{"region:fra"}})
+		}, false},
+	}
+
+	for _, c := range cases {
+		definition := map[string]interface{}{
+			"name":    "service",
+			"regions": []interface{}{"fra", "was"},
+			"docker": []interface{}{
+				map[string]interface{}{"image": "koyeb/demo"},
+			},
+			"instance_types": []interface{}{
+				map[string]interface{}{"type": "small", "scopes": []interface{}{"region:fra"}},
+				map[string]interface{}{"type": "nano", "scopes": []interface{}{"was"}},
+			},
+			"scalings": []interface{}{
+				map[string]interface{}{"min": 2, "max": 4, "scopes": []interface{}{"fra"}},
+				map[string]interface{}{"min": 1, "max": 1, "scopes": []interface{}{"region:was"}},
+			},
+			"env": []interface{}{
+				map[string]interface{}{"key": "LOG_LEVEL", "value": "info", "scopes": []interface{}{"region:was", "region:fra"}},
+				map[string]interface{}{"key": "DATABASE_URL", "value": "postgres://fra", "scopes": []interface{}{"fra"}},
+				map[string]interface{}{"key": "DATABASE_URL", "value": "postgres://was", "scopes": []interface{}{"was"}},
+			},
+		}
+		c.update(definition)
+		config := map[string]interface{}{
+			"app_name":   "app",
+			"definition": []interface{}{definition},
+		}
+
+		_, err := resourceKoyebService().Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), nil)
+		if c.valid && err != nil {
+			t.Errorf("%s: expected the scopes to be valid, got %s", c.name, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected the scopes to be rejected", c.name)
+		}
+	}
+}
+
+func TestAccKoyebService_Scopes(t *testing.T) {
+	var service koyeb.Service
+	var deployment koyeb.Deployment
+	appName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_scopes, appName, appName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					testAccCheckKoyebServiceLatestDeployment(&service, &deployment),
+					func(*terraform.State) error {
+						for _, instanceType := range deployment.Definition.InstanceTypes {
+							expected := map[string]string{"small": "region:fra", "nano": "region:was"}[instanceType.GetType()]
+							if !slices.Equal(instanceType.Scopes, []string{expected}) {
+								return fmt.Errorf("Expected the %s instance type to be scoped to %s, got %v", instanceType.GetType(), expected, instanceType.Scopes)
+							}
+						}
+						return nil
+					},
+				),
+			},
+			{
+				ResourceName:       "koyeb_service.bar",
+				ImportState:        true,
+				ImportStateVerify:  true,
+				ImportStatePersist: true,
+			},
+			{
+				// Scopes written as region names or covering all the regions
+				// don't show up as changes after an import
+				Config:   fmt.Sprintf(testAccCheckKoyebServiceConfig_scopes, appName, appName),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccKoyebService_VolumeScopes(t *testing.T) {
+	var service koyeb.Service
+	var deployment koyeb.Deployment
+	appName := randomTestName()
+	volumeName := randomTestName()
+
+	checkVolumeScopes := resource.ComposeTestCheckFunc(
+		testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+		testAccCheckKoyebServiceLatestDeployment(&service, &deployment),
+		func(*terraform.State) error {
+			if scopes := deployment.Definition.Volumes[0].Scopes; !slices.Equal(scopes, []string{"region:fra"}) {
+				return fmt.Errorf("Expected the volume to be scoped to region:fra, got %v", scopes)
+			}
+			return nil
+		},
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_volume_scope, appName, volumeName, appName, `["fra"]`),
+				Check:  checkVolumeScopes,
+			},
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_volume_scope, appName, volumeName, appName, `["region:fra"]`),
+				Check:  checkVolumeScopes,
+			},
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_volume_scope, appName, volumeName, appName, `[]`),
+				Check:  checkVolumeScopes,
+			},
+		},
+	})
+}
+
+func TestValidateScalings(t *testing.T) {
+	cases := []struct {
+		serviceType    string
+		instanceType   string
+		min            int
+		max            int
+		sleepIdleDelay int
+		valid          bool
+	}{
+		{"WEB", "nano", 0, 1, 0, true},
+		{"WEB", "nano", 0, 1, 300, true},
+		{"WEB", "nano", 0, 3, 300, true},
+		{"WEB", "free", 0, 1, 0, true},
+		{"WEB", "nano", 1, 1, 300, false},
+		{"WEB", "free", 0, 1, 300, false},
+		{"WEB", "free", 0, 2, 0, false},
+		{"WEB", "eco-nano", 1, 1, 0, true},
+		{"WEB", "eco-nano", 2, 2, 0, true},
+		{"WEB", "eco-nano", 0, 1, 0, false},
+		{"WEB", "eco-nano", 0, 1, 300, false},
+		{"WEB", "eco-nano", 1, 3, 0, false},
+		{"WORKER", "nano", 0, 1, 0, false},
+		{"WORKER", "nano", 1, 1, 0, true},
+		{"WORKER", "eco-nano", 1, 1, 0, true},
+		{"WEB", "nano", 0, 0, 0, false},
+		{"WEB", "nano", 0, -1, 0, false},
+	}
+
+	for _, c := range cases {
+		scaling := map[string]interface{}{"min": c.min, "max": c.max}
+		if c.sleepIdleDelay != 0 {
+			scaling["targets"] = []interface{}{
+				map[string]interface{}{
+					"sleep_idle_delay": []interface{}{map[string]interface{}{"value": c.sleepIdleDelay}},
+				},
+			}
+		}
+		config := terraform.NewResourceConfigRaw(map[string]interface{}{
+			"app_name": "app",
+			"definition": []interface{}{
+				map[string]interface{}{
+					"name":           "service",
+					"type":           c.serviceType,
+					"regions":        []interface{}{"fra"},
+					"instance_types": []interface{}{map[string]interface{}{"type": c.instanceType}},
+					"scalings":       []interface{}{scaling},
+					"docker":         []interface{}{map[string]interface{}{"image": "koyeb/demo"}},
+				},
+			},
+		})
+
+		diags := resourceKoyebService().Validate(config)
+		_, err := resourceKoyebService().Diff(context.Background(), nil, config, nil)
+		if c.valid && (diags.HasError() || err != nil) {
+			t.Errorf("expected %+v to be valid, got %v %v", c, diags, err)
+		}
+		if !c.valid && !diags.HasError() && err == nil {
+			t.Errorf("expected %+v to be rejected", c)
+		}
+	}
+}
+
+func TestAccKoyebService_ScaleToZero(t *testing.T) {
+	var service koyeb.Service
+	var deployment koyeb.Deployment
+	appName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_scale_to_zero, appName, appName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					testAccCheckKoyebServiceLatestDeployment(&service, &deployment),
+					func(*terraform.State) error {
+						scaling := deployment.Definition.Scalings[0]
+						if scaling.GetMin() != 0 || len(scaling.Targets) != 1 || scaling.Targets[0].SleepIdleDelay.GetValue() != 600 {
+							return fmt.Errorf("Expected the service to scale to zero after 600 seconds, got %+v", scaling)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestCustomizeServiceDiff(t *testing.T) {
+	ports := func(definition map[string]interface{}) {
+		definition["ports"] = []interface{}{
+			map[string]interface{}{"port": 3000, "protocol": "http"},
+		}
+	}
+
+	cases := []struct {
+		name   string
+		update func(definition map[string]interface{})
+		valid  bool
+	}{
+		{"routes and health checks on exposed ports", func(definition map[string]interface{}) {
+			ports(definition)
+			definition["routes"] = []interface{}{
+				map[string]interface{}{"port": 3000, "path": "/"},
+			}
+			definition["health_checks"] = []interface{}{
+				map[string]interface{}{
+					"http": []interface{}{map[string]interface{}{"port": 3000, "path": "/health"}},
+				},
+			}
+		}, true},
+		{"route on a port not exposed", func(definition map[string]interface{}) {
+			ports(definition)
+			definition["routes"] = []interface{}{
+				map[string]interface{}{"port": 8000, "path": "/"},
+			}
+		}, false},
+		{"health check on a port not exposed", func(definition map[string]interface{}) {
+			ports(definition)
+			definition["health_checks"] = []interface{}{
+				map[string]interface{}{
+					"tcp": []interface{}{map[string]interface{}{"port": 8000}},
+				},
+			}
+		}, false},
+		{"worker with routes", func(definition map[string]interface{}) {
+			ports(definition)
+			definition["type"] = "WORKER"
+			definition["routes"] = []interface{}{
+				map[string]interface{}{"port": 3000, "path": "/"},
+			}
+		}, false},
+		{"worker without routes", func(definition map[string]interface{}) {
+			definition["type"] = "WORKER"
+		}, true},
+		{"min greater than max", func(definition map[string]interface{}) {
+			definition["scalings"] = []interface{}{
+				map[string]interface{}{"min": 3, "max": 2},
+			}
+		}, false},
+		{"no source", func(definition map[string]interface{}) {
+			delete(definition, "docker")
+		}, false},
+		{"docker and git sources", func(definition map[string]interface{}) {
+			definition["git"] = []interface{}{
+				map[string]interface{}{"repository": "github.com/koyeb/example-flask", "branch": "main"},
+			}
+		}, false},
+		{"volume in a single region", func(definition map[string]interface{}) {
+			definition["volumes"] = []interface{}{
+				map[string]interface{}{"id": "volume-id", "path": "/data"},
+			}
+		}, true},
+		{"volume in several regions", func(definition map[string]interface{}) {
+			definition["regions"] = []interface{}{"fra", "was"}
+			definition["volumes"] = []interface{}{
+				map[string]interface{}{"id": "volume-id", "path": "/data"},
+			}
+		}, false},
+		{"volume with several instances", func(definition map[string]interface{}) {
+			definition["scalings"] = []interface{}{
+				map[string]interface{}{"min": 1, "max": 2},
+			}
+			definition["volumes"] = []interface{}{
+				map[string]interface{}{"id": "volume-id", "path": "/data"},
+			}
+		}, false},
+	}
+
+	for _, c := range cases {
+		definition := map[string]interface{}{
+			"name":    "service",
+			"regions": []interface{}{"fra"},
+			"docker": []interface{}{
+				map[string]interface{}{"image": "koyeb/demo"},
+			},
+			"instance_types": []interface{}{
+				map[string]interface{}{"type": "nano"},
+			},
+			"scalings": []interface{}{
+				map[string]interface{}{"min": 1, "max": 1},
+			},
+		}
+		c.update(definition)
+		config := map[string]interface{}{
+			"app_name":   "app",
+			"definition": []interface{}{definition},
+		}
+
+		_, err := resourceKoyebService().Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), nil)
+		if c.valid && err != nil {
+			t.Errorf("%s: expected the definition to be valid, got %s", c.name, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected the definition to be rejected", c.name)
+		}
+	}
+}
+
+func TestAccKoyebService_ComputedHealthChecks(t *testing.T) {
+	var service koyeb.Service
+	appName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_port, appName, appName, 3000),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "definition.0.health_checks.#", "1"),
+				),
+			},
+			{
+				// The health check computed on port 3000 is kept in the plan,
+				// but isn't configured so it doesn't make the port change
+				// invalid
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_port, appName, appName, 8000),
+				Check:  testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+			},
+		},
+	})
+}
+
+func TestAccKoyebService_PublicURLs(t *testing.T) {
+	var service koyeb.Service
+	appName := randomTestName()
+	domainName := appName + ".com"
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_routes, appName, domainName, appName, `routes {
+		  port = 8000
+		  path = "/api"
+		}
+
+		routes {
+		  port = 8000
+		  path = "/"
+		}`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					resource.TestMatchResourceAttr("koyeb_service.bar", "app_domain", regexp.MustCompile(`\.koyeb\.app$`)),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "public_urls.#", "4"),
+					resource.TestCheckTypeSetElemAttr("koyeb_service.bar", "public_urls.*", "https://"+domainName+"/"),
+					resource.TestCheckTypeSetElemAttr("koyeb_service.bar", "public_urls.*", "https://"+domainName+"/api"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "private_hostname", "service."+appName+".koyeb"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_routes, appName, domainName, appName, `routes {
+		  port = 8000
+		  path = "/"
+		}`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "public_urls.#", "2"),
+					resource.TestCheckTypeSetElemAttr("koyeb_service.bar", "public_urls.*", "https://"+domainName+"/"),
+				),
+			},
+		},
+	})
+}
+
+func TestSetServiceRouting(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceKoyebService().Schema, map[string]interface{}{})
+
+	setServiceRouting(d, &koyeb.Service{Name: toOpt("main")}, &koyeb.App{
+		Name: toOpt("my-app"),
+		Domains: []koyeb.Domain{
+			{Name: toOpt("my-app-org.koyeb.app"), Type: koyeb.DOMAINTYPE_AUTOASSIGNED.Ptr()},
+			{Name: toOpt("www.example.com"), Type: koyeb.DOMAINTYPE_CUSTOM.Ptr()},
+		},
+	}, &koyeb.Deployment{Definition: &koyeb.DeploymentDefinition{
+		Routes: []koyeb.DeploymentRoute{{Path: toOpt("/api")}, {Path: toOpt("/")}},
+	}})
+
+	if got := d.Get("app_domain").(string); got != "my-app-org.koyeb.app" {
+		t.Fatalf("unexpected app domain %q", got)
+	}
+	expected := []interface{}{
+		"https://my-app-org.koyeb.app/",
+		"https://my-app-org.koyeb.app/api",
+		"https://www.example.com/",
+		"https://www.example.com/api",
+	}
+	if got := d.Get("public_urls").([]interface{}); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected the public URLs %v, got %v", expected, got)
+	}
+	// The private hostname isn't exposed by the API, so its derived format is
+	// pinned here
+	if got := d.Get("private_hostname").(string); got != "main.my-app.koyeb" {
+		t.Fatalf("unexpected private hostname %q", got)
+	}
+}
+
+func TestAccKoyebService_Instances(t *testing.T) {
+	var service koyeb.Service
+	appName := randomTestName()
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckKoyebServiceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_instances, appName, appName, false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "instances.#", "3"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "instances.0.region", "fra"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "instances.1.region", "fra"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "instances.2.region", "was"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "instances.2.status", "HEALTHY"),
+					resource.TestCheckResourceAttrSet("koyeb_service.bar", "instances.2.allocated_at"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "instances.2.restart_count", "0"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "regional_deployments.#", "2"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "regional_deployments.0.region", "fra"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "regional_deployments.0.healthy_instances", "2"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "regional_deployments.1.region", "was"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "regional_deployments.1.healthy_instances", "1"),
+				),
+			},
+			{
+				// Paused services have no running instance
+				Config: fmt.Sprintf(testAccCheckKoyebServiceConfig_instances, appName, appName, true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckKoyebServiceExists("koyeb_service.bar", &service),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "instances.#", "0"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "regional_deployments.0.healthy_instances", "0"),
+					resource.TestCheckResourceAttr("koyeb_service.bar", "regional_deployments.1.healthy_instances", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestFlattenInstances(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	instance := func(id string, region string, replica int64, status koyeb.InstanceStatus, minutes int) koyeb.InstanceListItem {
+		return koyeb.InstanceListItem{
+			Id:                   toOpt(id),
+			Region:               toOpt(region),
+			RegionalDeploymentId: toOpt("regional-deployment-" + region),
+			ReplicaIndex:         toOpt(replica),
+			Status:               toOpt(status),
+			CreatedAt:            toOpt(start.Add(time.Duration(minutes) * time.Minute)),
+		}
+	}
+
+	// The instance replacing a crashed instance keeps its replica index
+	instances := flattenInstances([]koyeb.InstanceListItem{
+		instance("was-1", "was", 0, koyeb.INSTANCESTATUS_HEALTHY, 2),
+		instance("was-0", "was", 0, koyeb.INSTANCESTATUS_ERROR, 0),
+		instance("fra-0", "fra", 0, koyeb.INSTANCESTATUS_HEALTHY, 0),
+		instance("fra-1", "fra", 1, koyeb.INSTANCESTATUS_STARTING, 1),
+		instance("fra-2", "fra", 2, koyeb.INSTANCESTATUS_STOPPED, 1),
+	})
+
+	expected := []struct {
+		id       string
+		restarts int
+	}{{"fra-0", 0}, {"fra-1", 0}, {"was-1", 1}}
+	if len(instances) != len(expected) {
+		t.Fatalf("expected %d instances, got %v", len(expected), instances)
+	}
+	for i, e := range expected {
+		if instances[i]["id"] != e.id || instances[i]["restart_count"] != e.restarts {
+			t.Errorf("expected instance %d to be %s restarted %d times, got %v", i, e.id, e.restarts, instances[i])
+		}
+	}
+
+	regionalDeployments := flattenRegionalDeployments([]koyeb.RegionalDeploymentListItem{
+		{Id: toOpt("regional-deployment-was"), Region: toOpt("was")},
+		{Id: toOpt("regional-deployment-fra"), Region: toOpt("fra")},
+	}, instances)
+	for i, healthy := range []int{1, 1} {
+		if regionalDeployments[i]["healthy_instances"] != healthy {
+			t.Errorf("expected %d healthy instances in regional deployment %d, got %v", healthy, i, regionalDeployments[i])
+		}
+	}
+}
+
 func testAccCheckKoyebServiceDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(*koyeb.APIClient)
+	client := testAccProvider.Meta().(*providerMeta).client
 	targetStatus := []string{"DELETED", "DELETING"}
 	for _, rs := range s.RootModule().Resources {
 		if rs.Type != "koyeb_service" {
 			continue
 		}
 
-		err := waitForResourceStatus(client.ServicesApi.GetService(context.Background(), rs.Primary.ID).Execute, "Service", targetStatus, 1, false)
-		if err != nil {
-			return fmt.Errorf("Service still exists: %s ", err)
-		}
+		err := (&statusWaiter{
+			Name:             fmt.Sprintf("service %s", rs.Primary.ID),
+			Refresh:          serviceStatusFunc(client, rs.Primary.ID),
+			Target:           targetStatus,
+			NotFoundIsTarget: true,
+			Timeout:          time.Minute,
+		}).Wait(context.Background())
+		if err != nil {
+			return fmt.Errorf("Service still exists: %s ", err)
+		}
+
+	}
+
+	return nil
+}
+
+func testAccCheckKoyebServiceExists(n string, service *koyeb.Service) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("No Record ID is set")
+		}
+
+		client := testAccProvider.Meta().(*providerMeta).client
+
+		res, _, err := client.ServicesApi.GetService(context.Background(), rs.Primary.ID).Execute()
+
+		if err != nil {
+			return err
+		}
+
+		if *res.Service.Id != rs.Primary.ID {
+			return fmt.Errorf("Record not found")
+		}
+
+		a := res.GetService()
+		*service = a
+
+		return nil
+	}
+}
+
+func testAccCheckKoyebServiceLatestDeployment(service *koyeb.Service, deployment *koyeb.Deployment) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		client := testAccProvider.Meta().(*providerMeta).client
+
+		res, _, err := client.DeploymentsApi.GetDeployment(context.Background(), service.GetLatestDeploymentId()).Execute()
+		if err != nil {
+			return err
+		}
+
+		*deployment = res.GetDeployment()
+
+		return nil
+	}
+}
+
+const testAccCheckKoyebServiceConfig_basic_docker = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
+
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	definition {
+		name = "service"
+		instance_types {
+		  type = "micro"
+		}
+		ports {
+		  port     = 3000
+		  protocol = "http"
+		}
+		scalings {
+		  min = 1
+		  max = 1
+		}
+		env {
+		  key   = "FOO"
+		  value = "BAR"
+		}
+		routes {
+		  path = "/"
+		  port = 3000
+		}
+		health_checks {
+		  http {
+		    path = "/"
+		    port = 3000
+			headers {
+				key = "X-Header"
+				value = "Value"
+			}
+		  }
+		}
+		regions = ["tyo"]
+		docker {
+		  image = "koyeb/demo"
+		}
+	}
+
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
+
+const testAccCheckKoyebServiceConfig_basic_git_buildpack = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
+
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	definition {
+		name = "service"
+		instance_types {
+
+		  type = "micro"
+		}
+		ports {
+		  port     = 8080
+		  protocol = "http"
+		}
+		scalings {
+		  min = 1
+		  max = 1
+		}
+		env {
+		  key   = "FOO"
+		  value = "BAR"
+		}
+		routes {
+		  path = "/"
+		  port = 8080
+		}
+		health_checks {
+		  tcp {
+			port = 8080
+		  }
+		}
+		regions = ["fra"]
+		git {
+		  repository = "github.com/koyeb/example-flask"
+		  branch = "main"
+		  buildpack {}
+		}
+	}
+
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
+
+const testAccCheckKoyebServiceConfig_basic_git_dockerfile = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
+
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	definition {
+		name = "service"
+		instance_types {
+
+		  type = "micro"
+		}
+		type = "WORKER"
+		scalings {
+		  min = 1
+		  max = 1
+		}
+		env {
+		  key   = "FOO"
+		  value = "BAR"
+		}
+		regions = ["fra", "tyo"]
+		git {
+		  repository = "github.com/koyeb/example-flask"
+		  branch = "main"
+		  dockerfile {}
+		}
+	}
+
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
+
+const testAccCheckKoyebServiceConfig_paused = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
+
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	paused   = %t
+	definition {
+		name = "service"
+		instance_types {
+		  type = "micro"
+		}
+		ports {
+		  port     = 3000
+		  protocol = "http"
+		}
+		scalings {
+		  min = 1
+		  max = 1
+		}
+		routes {
+		  path = "/"
+		  port = 3000
+		}
+		regions = ["fra"]
+		docker {
+		  image = "koyeb/demo"
+		}
+	}
+
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
+
+const testAccCheckKoyebServiceConfig_redeploy_triggers = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
+
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	redeploy_triggers = {
+		image = "%s"
+	}
+	definition {
+		name = "service"
+		instance_types {
+		  type = "micro"
+		}
+		scalings {
+		  min = 1
+		  max = 1
+		}
+		regions = ["fra"]
+		docker {
+		  image = "koyeb/demo"
+		}
+	}
+
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
+
+const testAccCheckKoyebServiceConfig_git_ref = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
+
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	definition {
+		name = "service"
+		instance_types {
+		  type = "micro"
+		}
+		type = "WORKER"
+		scalings {
+		  min = 1
+		  max = 1
+		}
+		regions = ["fra"]
+		git {
+		  repository = "github.com/koyeb/example-flask"
+		  %s
+		  buildpack {}
+		}
+	}
+
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
+
+const testAccCheckKoyebServiceConfig_archive = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
 
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	definition {
+		name = "service"
+		instance_types {
+		  type = "micro"
+		}
+		type = "WORKER"
+		scalings {
+		  min = 1
+		  max = 1
+		}
+		regions = ["fra"]
+		archive {
+		  path            = "%s"
+		  ignore_patterns = ["*.log"]
+		  buildpack {}
+		}
 	}
 
-	return nil
-}
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
 
-func testAccCheckKoyebServiceExists(n string, service *koyeb.Service) resource.TestCheckFunc {
-	return func(s *terraform.State) error {
-		rs, ok := s.RootModule().Resources[n]
+const testAccCheckKoyebServiceConfig_strategy = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
 
-		if !ok {
-			return fmt.Errorf("Not found: %s", n)
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	definition {
+		name = "service"
+		type = "%s"
+		instance_types {
+		  type = "micro"
 		}
-
-		if rs.Primary.ID == "" {
-			return fmt.Errorf("No Record ID is set")
+		scalings {
+		  min = 1
+		  max = 1
 		}
+		regions = ["fra"]
+		docker {
+		  image = "koyeb/demo"
+		}
+		%s
+	}
 
-		client := testAccProvider.Meta().(*koyeb.APIClient)
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
 
-		res, _, err := client.ServicesApi.GetService(context.Background(), rs.Primary.ID).Execute()
+const testAccCheckKoyebServiceConfig_config_file = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
 
-		if err != nil {
-			return err
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	definition {
+		name = "service"
+		instance_types {
+		  type = "micro"
 		}
-
-		if *res.Service.Id != rs.Primary.ID {
-			return fmt.Errorf("Record not found")
+		scalings {
+		  min = 1
+		  max = 1
+		}
+		regions = ["fra"]
+		docker {
+		  image = "koyeb/demo"
+		}
+		config_file {
+		  path    = "/etc/prometheus/targets.json"
+		  content = %q
 		}
+	}
 
-		a := res.GetService()
-		*service = a
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
 
-		return nil
-	}
+const testAccCheckKoyebServiceConfig_scopes = `
+resource "koyeb_app" "foo" {
+	name = "%s"
 }
 
-const testAccCheckKoyebServiceConfig_basic_docker = `
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	definition {
+		name = "service"
+		type = "WORKER"
+		instance_types {
+		  type   = "small"
+		  scopes = ["region:fra"]
+		}
+		instance_types {
+		  type   = "nano"
+		  scopes = ["was"]
+		}
+		scalings {
+		  min    = 2
+		  max    = 4
+		  scopes = ["fra"]
+		}
+		scalings {
+		  min    = 1
+		  max    = 1
+		  scopes = ["region:was"]
+		}
+		env {
+		  key    = "LOG_LEVEL"
+		  value  = "info"
+		  scopes = ["region:was", "region:fra"]
+		}
+		env {
+		  key    = "DATABASE_URL"
+		  value  = "postgres://fra"
+		  scopes = ["fra"]
+		}
+		env {
+		  key    = "DATABASE_URL"
+		  value  = "postgres://was"
+		  scopes = ["was"]
+		}
+		regions = ["fra", "was"]
+		docker {
+		  image = "koyeb/demo"
+		}
+	}
+
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
+
+const testAccCheckKoyebServiceConfig_volume_scope = `
 resource "koyeb_app" "foo" {
 	name = "%s"
 }
 
+resource "koyeb_volume" "foo" {
+	name     = "%s"
+	max_size = 10
+	region   = "fra"
+}
+
 resource "koyeb_service" "bar" {
 	app_name = "%s"
 	definition {
 		name = "service"
+		type = "WORKER"
 		instance_types {
 		  type = "micro"
 		}
-		ports {
-		  port     = 3000
-		  protocol = "http"
-		}
 		scalings {
 		  min = 1
 		  max = 1
 		}
+		volumes {
+		  id    = koyeb_volume.foo.id
+		  path  = "/data"
+		  scope = %s
+		}
+		regions = ["fra"]
+		docker {
+		  image = "koyeb/demo"
+		}
+	}
+
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
+
+const testAccCheckKoyebServiceConfig_single_region_scopes = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
+
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	definition {
+		name = "service"
+		type = "WORKER"
+		instance_types {
+		  type   = "micro"
+		  scopes = ["region:fra"]
+		}
+		scalings {
+		  min    = 1
+		  max    = 1
+		  scopes = ["region:fra"]
+		}
 		env {
-		  key   = "FOO"
-		  value = "BAR"
+		  key    = "FOO"
+		  value  = "BAR"
+		  scopes = ["region:fra"]
+		}
+		regions = ["fra"]
+		docker {
+		  image = "koyeb/demo"
+		}
+	}
+
+	depends_on = [
+	  koyeb_app.foo
+	]
+}`
+
+const testAccCheckKoyebServiceConfig_scale_to_zero = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
+
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	definition {
+		name = "service"
+		instance_types {
+		  type = "nano"
+		}
+		ports {
+		  port     = 3000
+		  protocol = "http"
 		}
 		routes {
 		  path = "/"
 		  port = 3000
 		}
-		health_checks {
-		  http {
-		    path = "/"
-		    port = 3000
-			headers {
-				key = "X-Header"
-				value = "Value"
-			}
+		scalings {
+		  min = 0
+		  max = 1
+		  targets {
+		    sleep_idle_delay {
+		      value = 600
+		    }
 		  }
 		}
-		regions = ["tyo"]
+		regions = ["fra"]
 		docker {
 		  image = "koyeb/demo"
 		}
@@ -215,7 +1732,7 @@ resource "koyeb_service" "bar" {
 	]
 }`
 
-const testAccCheckKoyebServiceConfig_basic_git_buildpack = `
+const testAccCheckKoyebServiceConfig_port = `
 resource "koyeb_app" "foo" {
 	name = "%s"
 }
@@ -225,35 +1742,19 @@ resource "koyeb_service" "bar" {
 	definition {
 		name = "service"
 		instance_types {
-
 		  type = "micro"
 		}
 		ports {
-		  port     = 8080
+		  port     = %d
 		  protocol = "http"
 		}
 		scalings {
 		  min = 1
 		  max = 1
 		}
-		env {
-		  key   = "FOO"
-		  value = "BAR"
-		}
-		routes {
-		  path = "/"
-		  port = 8080
-		}
-		health_checks {
-		  tcp {
-			port = 8080
-		  }
-		}
 		regions = ["fra"]
-		git {
-		  repository = "github.com/koyeb/example-flask"
-		  branch = "main"
-		  buildpack {}
+		docker {
+		  image = "koyeb/demo"
 		}
 	}
 
@@ -262,33 +1763,69 @@ resource "koyeb_service" "bar" {
 	]
 }`
 
-const testAccCheckKoyebServiceConfig_basic_git_dockerfile = `
+const testAccCheckKoyebServiceConfig_routes = `
 resource "koyeb_app" "foo" {
 	name = "%s"
 }
 
+resource "koyeb_domain" "foo" {
+	name     = "%s"
+	app_name = koyeb_app.foo.name
+}
+
 resource "koyeb_service" "bar" {
 	app_name = "%s"
 	definition {
 		name = "service"
 		instance_types {
-
 		  type = "micro"
 		}
-		type = "WORKER"
 		scalings {
 		  min = 1
 		  max = 1
 		}
-		env {
-		  key   = "FOO"
-		  value = "BAR"
+		ports {
+		  port     = 8000
+		  protocol = "http"
 		}
-		regions = ["fra", "tyo"]
-		git {
-		  repository = "github.com/koyeb/example-flask"
-		  branch = "main"
-		  dockerfile {}
+		%s
+		regions = ["fra"]
+		docker {
+		  image = "koyeb/demo"
+		}
+	}
+
+	depends_on = [
+	  koyeb_domain.foo
+	]
+}`
+
+const testAccCheckKoyebServiceConfig_instances = `
+resource "koyeb_app" "foo" {
+	name = "%s"
+}
+
+resource "koyeb_service" "bar" {
+	app_name = "%s"
+	paused   = %t
+	definition {
+		name = "service"
+		instance_types {
+		  type = "micro"
+		}
+		scalings {
+		  min    = 2
+		  max    = 2
+		  scopes = ["region:fra"]
+		}
+		scalings {
+		  min    = 1
+		  max    = 1
+		  scopes = ["region:was"]
+		}
+		regions = ["fra", "was"]
+		docker {
+		  image = "koyeb/demo"
 		}
 	}
 
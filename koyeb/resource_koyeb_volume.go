@@ -2,13 +2,14 @@ package koyeb
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
-	"github.com/koyeb/koyeb-cli/pkg/koyeb/idmapper"
 )
 
 func volumeSchema() map[string]*schema.Schema {
@@ -55,6 +56,7 @@ func volumeSchema() map[string]*schema.Schema {
 			ForceNew:    true,
 			Required:    true,
 		},
+		"deletion_protection": deletionProtectionSchema("volume"),
 		"read_only": {
 			Type:        schema.TypeBool,
 			Optional:    true,
@@ -106,9 +108,17 @@ func resourceKoyebVolume() *schema.Resource {
 		DeleteContext: resourceKoyebVolumeDelete,
 
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importStateWithDeletionProtection,
 		},
 
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		CustomizeDiff: customizeDeletionProtectionDiff("volume", volumeSchema()),
+
 		Schema: volumeSchema(),
 	}
 }
@@ -133,9 +143,9 @@ func setVolumeAttribute(d *schema.ResourceData, volume koyeb.PersistentVolume) e
 }
 
 func resourceKoyebVolumeCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	client := meta.(*providerMeta).client
 
-	res, resp, err := client.PersistentVolumesApi.CreatePersistentVolume(context.Background()).Body(koyeb.CreatePersistentVolumeRequest{
+	res, _, err := client.PersistentVolumesApi.CreatePersistentVolume(ctx).Body(koyeb.CreatePersistentVolumeRequest{
 		Name:       toOpt(d.Get("name").(string)),
 		VolumeType: toOpt(koyeb.PersistentVolumeBackingStore(d.Get("volume_type").(string))),
 		MaxSize:    toOpt(int64(d.Get("max_size").(int))),
@@ -143,23 +153,27 @@ func resourceKoyebVolumeCreate(ctx context.Context, d *schema.ResourceData, meta
 	}).Execute()
 
 	if err != nil {
-		return diag.Errorf("Error creating volume: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error creating volume", err, resourceKoyebVolume().Schema)
 	}
 
 	d.SetId(*res.Volume.Id)
+	meta.(*providerMeta).resolver.Invalidate(volumeKind)
 	log.Printf("[INFO] Created volume name: %s", *res.Volume.Name)
 
+	if err := waitForVolume(ctx, client, d.Id(), d.Timeout(schema.TimeoutCreate)); err != nil {
+		return diag.Errorf("Error waiting for volume creation: %s", err)
+	}
+
 	return resourceKoyebVolumeRead(ctx, d, meta)
 }
 
 func resourceKoyebVolumeRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
-	mapper := idmapper.NewMapper(context.Background(), client)
-	volumeMapper := mapper.Volume()
+	client := meta.(*providerMeta).client
+	resolver := meta.(*providerMeta).resolver
 	var volumeId string
 
 	if d.Id() != "" {
-		id, err := volumeMapper.ResolveID(d.Id())
+		id, err := resolver.ResolveID(ctx, volumeKind, d.Id())
 
 		if err != nil {
 			return diag.Errorf("Error retrieving volume: %s", err)
@@ -168,16 +182,16 @@ func resourceKoyebVolumeRead(ctx context.Context, d *schema.ResourceData, meta i
 		volumeId = id
 	}
 
-	res, resp, err := client.PersistentVolumesApi.GetPersistentVolume(context.Background(), volumeId).Execute()
+	res, resp, err := client.PersistentVolumesApi.GetPersistentVolume(ctx, volumeId).Execute()
 	if err != nil {
 		// If the volume is somehow already destroyed, mark as
 		// successfully gone
-		if resp.StatusCode == 404 {
+		if isNotFound(resp) {
 			d.SetId("")
 			return nil
 		}
 
-		return diag.Errorf("Error retrieving volume: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error retrieving volume", err, resourceKoyebVolume().Schema)
 	}
 
 	setVolumeAttribute(d, *res.Volume)
@@ -186,29 +200,59 @@ func resourceKoyebVolumeRead(ctx context.Context, d *schema.ResourceData, meta i
 }
 
 func resourceKoyebVolumeUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	client := meta.(*providerMeta).client
 
-	res, resp, err := client.PersistentVolumesApi.UpdatePersistentVolume(context.Background(), d.Id()).Body(koyeb.UpdatePersistentVolumeRequest{
+	// The deletion protection is only stored in the state
+	if !d.HasChangeExcept("deletion_protection") {
+		return resourceKoyebVolumeRead(ctx, d, meta)
+	}
+
+	res, _, err := client.PersistentVolumesApi.UpdatePersistentVolume(ctx, d.Id()).Body(koyeb.UpdatePersistentVolumeRequest{
 		Name:    toOpt(d.Get("name").(string)),
 		MaxSize: toOpt(int64(d.Get("max_size").(int))),
 	}).Execute()
 
 	if err != nil {
-		return diag.Errorf("Error updating volume: %s (%v %v)", err, resp, res)
+		return apiErrorDiagnostics("Error updating volume", err, resourceKoyebVolume().Schema)
+	}
+
+	if d.HasChange("name") {
+		meta.(*providerMeta).resolver.Invalidate(volumeKind)
 	}
 
 	log.Printf("[INFO] Updated volume name: %s", *res.Volume.Name)
 
+	if err := waitForVolume(ctx, client, d.Id(), d.Timeout(schema.TimeoutUpdate)); err != nil {
+		return diag.Errorf("Error waiting for volume update: %s", err)
+	}
+
 	return resourceKoyebVolumeRead(ctx, d, meta)
 }
 
 func resourceKoyebVolumeDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*koyeb.APIClient)
+	if diags := checkDeletionProtection(d, "volume"); diags.HasError() {
+		return diags
+	}
+
+	client := meta.(*providerMeta).client
+
+	_, _, err := client.PersistentVolumesApi.DeletePersistentVolume(ctx, d.Id()).Execute()
+
+	if err != nil {
+		return apiErrorDiagnostics("Error deleting volume", err, resourceKoyebVolume().Schema)
+	}
 
-	res, resp, err := client.PersistentVolumesApi.DeletePersistentVolume(context.Background(), d.Id()).Execute()
+	meta.(*providerMeta).resolver.Invalidate(volumeKind)
 
+	err = (&statusWaiter{
+		Name:             fmt.Sprintf("volume %s", d.Id()),
+		Refresh:          volumeStatusFunc(client, d.Id()),
+		Target:           []string{string(koyeb.PERSISTENTVOLUMESTATUS_DELETED)},
+		NotFoundIsTarget: true,
+		Timeout:          d.Timeout(schema.TimeoutDelete),
+	}).Wait(ctx)
 	if err != nil {
-		return diag.Errorf("Error deleting volume: %s (%v %v)", err, resp, res)
+		return diag.Errorf("Error waiting for volume deletion: %s", err)
 	}
 
 	d.SetId("")
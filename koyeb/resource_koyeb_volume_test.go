@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
@@ -45,7 +46,7 @@ func TestAccKoyebVolume_Basic(t *testing.T) {
 }
 
 func testAccCheckKoyebVolumeDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(*koyeb.APIClient)
+	client := testAccProvider.Meta().(*providerMeta).client
 	targetStatus := []string{"DELETED", "DELETING"}
 
 	for _, rs := range s.RootModule().Resources {
@@ -53,8 +54,14 @@ func testAccCheckKoyebVolumeDestroy(s *terraform.State) error {
 			continue
 		}
 
-		err := waitForResourceStatus(client.PersistentVolumesApi.GetPersistentVolume(context.Background(), rs.Primary.ID).Execute, "Volume", targetStatus, 1, false)
-		if err == nil {
+		err := (&statusWaiter{
+			Name:             fmt.Sprintf("volume %s", rs.Primary.ID),
+			Refresh:          volumeStatusFunc(client, rs.Primary.ID),
+			Target:           targetStatus,
+			NotFoundIsTarget: true,
+			Timeout:          time.Minute,
+		}).Wait(context.Background())
+		if err != nil {
 			return fmt.Errorf("Volume still exists: %s ", err)
 		}
 	}
@@ -84,7 +91,7 @@ func testAccCheckKoyebVolumeExists(n string, volume *koyeb.PersistentVolume) res
 			return fmt.Errorf("No Record ID is set")
 		}
 
-		client := testAccProvider.Meta().(*koyeb.APIClient)
+		client := testAccProvider.Meta().(*providerMeta).client
 
 		res, _, err := client.PersistentVolumesApi.GetPersistentVolume(context.Background(), rs.Primary.ID).Execute()
 
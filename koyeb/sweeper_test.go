@@ -1,17 +1,42 @@
 package koyeb
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+	"github.com/koyeb/terraform-provider-koyeb/koyebtest"
 )
 
 func TestMain(m *testing.M) {
-	resource.TestMain(m)
+	// Run the acceptance tests against an in-memory fake of the Koyeb API,
+	// which requires neither network access nor a Koyeb account
+	if os.Getenv("KOYEB_TEST_FAKE_API") == "" {
+		resource.TestMain(m)
+		return
+	}
+
+	server := koyebtest.NewServer()
+	os.Setenv("KOYEB_API_URL", server.URL)
+	os.Setenv("KOYEB_TOKEN", server.Token)
+	os.Unsetenv("KOYEB_ORGANIZATION_ID")
+
+	// resource.TestMain exits once the tests have run, so the server is
+	// closed by the runner it is given
+	resource.TestMain(testRunFunc(func() int {
+		defer server.Close()
+		return m.Run()
+	}))
+}
+
+// testRunFunc runs tests, as testing.M does.
+type testRunFunc func() int
+
+func (f testRunFunc) Run() int {
+	return f()
 }
 
 func sharedConfig() (interface{}, error) {
@@ -22,16 +47,16 @@ func sharedConfig() (interface{}, error) {
 	p := schema.Provider{}
 	userAgent := p.UserAgent("terraform-provider-koyeb", "test")
 
-	apiHost := os.Getenv("KOYEB_API_URL")
-	if apiHost == "" {
-		apiHost = "app.koyeb.com"
+	apiURL := os.Getenv("KOYEB_API_URL")
+	if apiURL == "" {
+		apiURL = defaultAPIURL
 	}
 
-	koyebClientConfig := koyeb.NewConfiguration()
-	koyebClientConfig.DefaultHeader["Authorization"] = "Bearer " + os.Getenv("KOYEB_TOKEN")
-	koyebClientConfig.UserAgent = userAgent
-
-	client := koyeb.NewAPIClient(koyebClientConfig)
-
-	return client, nil
+	return newAPIClient(context.Background(), clientConfig{
+		Token:          os.Getenv("KOYEB_TOKEN"),
+		APIURL:         apiURL,
+		OrganizationID: os.Getenv("KOYEB_ORGANIZATION_ID"),
+		UserAgent:      userAgent,
+		MaxRetries:     defaultMaxRetries,
+	})
 }
@@ -0,0 +1,153 @@
+package koyeb
+
+import (
+	"context"
+	"io"
+	"log"
+	"math"
+	_nethttp "net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 5
+	defaultMinBackoff = 1 * time.Second
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// retryTransport is an http.RoundTripper retrying requests which failed
+// because of rate limiting or transient errors.
+//
+// Idempotent requests are retried on network errors and on 429, 502, 503 and
+// 504 responses. Other requests are only retried on 429 responses, since the
+// API rejects rate limited requests before processing them. The delay between
+// attempts follows the Retry-After header when the API sends one, and an
+// exponential backoff with jitter otherwise, both bounded by MaxBackoff.
+type retryTransport struct {
+	Base       _nethttp.RoundTripper
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func newRetryTransport(base _nethttp.RoundTripper, maxRetries int) *retryTransport {
+	if base == nil {
+		base = _nethttp.DefaultTransport
+	}
+	return &retryTransport{
+		Base:       base,
+		MaxRetries: maxRetries,
+		MinBackoff: defaultMinBackoff,
+		MaxBackoff: defaultMaxBackoff,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *_nethttp.Request) (*_nethttp.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := t.Base.RoundTrip(req)
+
+		if attempt >= t.MaxRetries || !t.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		if err != nil {
+			log.Printf("[DEBUG] %s %s failed: %s, retrying in %s", req.Method, req.URL, err, delay)
+		} else {
+			log.Printf("[DEBUG] %s %s returned %d, retrying in %s", req.Method, req.URL, resp.StatusCode, delay)
+			// Drain the body so the connection can be reused
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := sleepContext(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *retryTransport) shouldRetry(req *_nethttp.Request, resp *_nethttp.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	// The body of the request can't be sent again
+	if req.Body != nil && req.Body != _nethttp.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		return isIdempotent(req.Method)
+	}
+
+	switch resp.StatusCode {
+	case _nethttp.StatusTooManyRequests:
+		return true
+	case _nethttp.StatusBadGateway, _nethttp.StatusServiceUnavailable, _nethttp.StatusGatewayTimeout:
+		return isIdempotent(req.Method)
+	}
+	return false
+}
+
+// backoff returns the delay to wait before the next attempt.
+func (t *retryTransport) backoff(attempt int, resp *_nethttp.Response) time.Duration {
+	if resp != nil {
+		// The header is capped so a misbehaving server can't stall the
+		// provider for hours
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(delay, t.MaxBackoff)
+		}
+	}
+
+	delay := time.Duration(float64(t.MinBackoff) * math.Pow(2, float64(attempt)))
+	if delay <= 0 || delay > t.MaxBackoff {
+		delay = t.MaxBackoff
+	}
+	return withJitter(delay)
+}
+
+// parseRetryAfter parses a Retry-After header, which holds either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := _nethttp.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case _nethttp.MethodGet, _nethttp.MethodHead, _nethttp.MethodOptions, _nethttp.MethodPut, _nethttp.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
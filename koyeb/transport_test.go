@@ -0,0 +1,206 @@
+package koyeb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+// flakyServer fails the first failures requests with status, then returns an
+// empty app.
+func flakyServer(t *testing.T, failures int32, status int, retryAfter string) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&calls, 1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"status": %d, "code": "error", "message": "error"}`, status)
+			return
+		}
+		fmt.Fprint(w, `{"app": {"id": "app-id", "name": "app"}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newRetryTestClient(url string, maxRetries int) *koyeb.APIClient {
+	config := koyeb.NewConfiguration()
+	config.Servers[0].URL = url
+	config.HTTPClient = &http.Client{
+		Transport: &retryTransport{
+			Base:       http.DefaultTransport,
+			MaxRetries: maxRetries,
+			MinBackoff: time.Millisecond,
+			MaxBackoff: 5 * time.Millisecond,
+		},
+	}
+	return koyeb.NewAPIClient(config)
+}
+
+func TestRetryTransport_RetriesTransientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		server, calls := flakyServer(t, 2, status, "")
+		client := newRetryTestClient(server.URL, 3)
+
+		res, _, err := client.AppsApi.GetApp(context.Background(), "app-id").Execute()
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %s", status, err)
+		}
+		if res.App.GetId() != "app-id" {
+			t.Fatalf("%d: unexpected app: %v", status, res)
+		}
+		if *calls != 3 {
+			t.Fatalf("%d: expected 3 calls, got %d", status, *calls)
+		}
+	}
+}
+
+func TestRetryTransport_MaxRetries(t *testing.T) {
+	server, calls := flakyServer(t, 10, http.StatusServiceUnavailable, "")
+	client := newRetryTestClient(server.URL, 2)
+
+	_, resp, err := client.AppsApi.GetApp(context.Background(), "app-id").Execute()
+	if err == nil {
+		t.Fatal("expected an error once the retries are exhausted")
+	}
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected the last response to be returned, got %v", resp)
+	}
+	if *calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", *calls)
+	}
+}
+
+func TestRetryTransport_NonIdempotentRequests(t *testing.T) {
+	server, calls := flakyServer(t, 1, http.StatusServiceUnavailable, "")
+	client := newRetryTestClient(server.URL, 3)
+
+	_, _, err := client.AppsApi.CreateApp(context.Background()).App(koyeb.CreateApp{Name: toOpt("app")}).Execute()
+	if err == nil {
+		t.Fatal("expected a POST failing with 503 not to be retried")
+	}
+	if *calls != 1 {
+		t.Fatalf("expected 1 call, got %d", *calls)
+	}
+
+	server, calls = flakyServer(t, 1, http.StatusTooManyRequests, "")
+	client = newRetryTestClient(server.URL, 3)
+
+	_, _, err = client.AppsApi.CreateApp(context.Background()).App(koyeb.CreateApp{Name: toOpt("app")}).Execute()
+	if err != nil {
+		t.Fatalf("expected a rate limited POST to be retried, got: %s", err)
+	}
+	if *calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", *calls)
+	}
+}
+
+func TestRetryTransport_RetryAfter(t *testing.T) {
+	server, calls := flakyServer(t, 1, http.StatusTooManyRequests, "1")
+	client := newRetryTestClient(server.URL, 3)
+	client.GetConfig().HTTPClient.Transport.(*retryTransport).MaxBackoff = 2 * time.Second
+
+	start := time.Now()
+	_, _, err := client.AppsApi.GetApp(context.Background(), "app-id").Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("expected Retry-After to be honored, retried after %s", elapsed)
+	}
+	if *calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", *calls)
+	}
+}
+
+func TestRetryTransport_RetryAfterCapped(t *testing.T) {
+	server, calls := flakyServer(t, 1, http.StatusTooManyRequests, "3600")
+	client := newRetryTestClient(server.URL, 3)
+
+	start := time.Now()
+	_, _, err := client.AppsApi.GetApp(context.Background(), "app-id").Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected Retry-After to be capped by MaxBackoff, retried after %s", elapsed)
+	}
+	if *calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", *calls)
+	}
+}
+
+func TestRetryTransport_ContextCanceled(t *testing.T) {
+	server, calls := flakyServer(t, 10, http.StatusTooManyRequests, "3600")
+	client := newRetryTestClient(server.URL, 3)
+	client.GetConfig().HTTPClient.Transport.(*retryTransport).MaxBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err := client.AppsApi.GetApp(ctx, "app-id").Execute()
+	if err == nil {
+		t.Fatal("expected an error when the context is canceled")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("retry did not honor the context, took %s", elapsed)
+	}
+	if *calls != 1 {
+		t.Fatalf("expected 1 call, got %d", *calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("2"); !ok || d != 2*time.Second {
+		t.Fatalf("unexpected delay for seconds: %s %v", d, ok)
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(date); !ok || d <= 0 || d > time.Hour {
+		t.Fatalf("unexpected delay for a date: %s %v", d, ok)
+	}
+	for _, value := range []string{"", "-1", "soon"} {
+		if _, ok := parseRetryAfter(value); ok {
+			t.Fatalf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestProvider_MaxRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	p := New("test")()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"token":       "token",
+		"api_url":     server.URL,
+		"max_retries": 1,
+	}))
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	client := p.Meta().(*providerMeta).client
+	_, _, err := client.AppsApi.GetApp(context.Background(), "app-id").Execute()
+	if err == nil {
+		t.Fatal("expected an error once the retries are exhausted")
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+}
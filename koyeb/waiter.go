@@ -0,0 +1,329 @@
+package koyeb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	_nethttp "net/http"
+	"strings"
+	"time"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	defaultWaitMinInterval = 2 * time.Second
+	defaultWaitMaxInterval = 30 * time.Second
+)
+
+var (
+	deploymentPendingStatus = []string{
+		string(koyeb.DEPLOYMENTSTATUS_PENDING),
+		string(koyeb.DEPLOYMENTSTATUS_PROVISIONING),
+		string(koyeb.DEPLOYMENTSTATUS_SCHEDULED),
+		string(koyeb.DEPLOYMENTSTATUS_ALLOCATING),
+		string(koyeb.DEPLOYMENTSTATUS_STARTING),
+		string(koyeb.DEPLOYMENTSTATUS_DEGRADED),
+		string(koyeb.DEPLOYMENTSTATUS_CANCELING),
+		string(koyeb.DEPLOYMENTSTATUS_ERRORING),
+	}
+	deploymentTargetStatus = []string{
+		string(koyeb.DEPLOYMENTSTATUS_HEALTHY),
+		// Services scaling to zero go to sleep once they are healthy and idle
+		string(koyeb.DEPLOYMENTSTATUS_SLEEPING),
+	}
+	deploymentFailureStatus = []string{
+		string(koyeb.DEPLOYMENTSTATUS_ERROR),
+		string(koyeb.DEPLOYMENTSTATUS_UNHEALTHY),
+	}
+	// deploymentStoppedStatus are the statuses of a deployment stopped without
+	// failing, which happens when a newer deployment of the service replaces
+	// it, or cancels it before it is healthy
+	deploymentStoppedStatus = []string{
+		string(koyeb.DEPLOYMENTSTATUS_CANCELED),
+		string(koyeb.DEPLOYMENTSTATUS_STOPPING),
+		string(koyeb.DEPLOYMENTSTATUS_STOPPED),
+		string(koyeb.DEPLOYMENTSTATUS_STASHED),
+	}
+)
+
+// statusFunc returns the current status and status messages of a Koyeb
+// object. The HTTP response, which may be nil, is returned alongside errors so
+// the waiter can tell a missing object from a failed request.
+type statusFunc func(ctx context.Context) (status string, messages []string, resp *_nethttp.Response, err error)
+
+// statusWaiter polls a Koyeb object until it reaches one of the Target
+// statuses.
+//
+// The waiter fails as soon as the object reaches one of the Failure statuses.
+// When Pending is set, any status which is neither pending, target nor failure
+// is considered unexpected and also stops the waiter. Polls are spaced with an
+// exponential backoff with jitter, bounded by MinInterval and MaxInterval.
+type statusWaiter struct {
+	// Name identifies the object in error messages, e.g. "service 1234".
+	Name    string
+	Refresh statusFunc
+
+	Pending []string
+	Target  []string
+	Failure []string
+
+	// NotFoundIsTarget considers the target reached when the object no longer
+	// exists, which is what is expected when waiting for a deletion.
+	NotFoundIsTarget bool
+
+	Timeout     time.Duration
+	MinInterval time.Duration
+	MaxInterval time.Duration
+}
+
+// Wait blocks until the object reaches a target status, a failure status, the
+// timeout expires or ctx is done.
+func (w *statusWaiter) Wait(ctx context.Context) error {
+	minInterval := w.MinInterval
+	if minInterval <= 0 {
+		minInterval = defaultWaitMinInterval
+	}
+	maxInterval := w.MaxInterval
+	if maxInterval < minInterval {
+		maxInterval = max(defaultWaitMaxInterval, minInterval)
+	}
+
+	if w.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.Timeout)
+		defer cancel()
+	}
+
+	var status string
+	var messages []string
+	interval := minInterval
+
+	for {
+		s, m, resp, err := w.Refresh(ctx)
+		if err != nil {
+			if w.NotFoundIsTarget && resp != nil && resp.StatusCode == _nethttp.StatusNotFound {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return w.timeoutError(ctx, status, messages)
+			}
+			return fmt.Errorf("error retrieving %s: %w", w.Name, err)
+		}
+		status, messages = s, m
+
+		switch {
+		case slices.Contains(w.Target, status):
+			return nil
+		case slices.Contains(w.Failure, status):
+			return fmt.Errorf("%s reached status %s%s", w.Name, status, formatStatusMessages(messages))
+		case len(w.Pending) > 0 && !slices.Contains(w.Pending, status):
+			return fmt.Errorf("%s reached unexpected status %s%s", w.Name, status, formatStatusMessages(messages))
+		}
+
+		timer := time.NewTimer(withJitter(interval))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return w.timeoutError(ctx, status, messages)
+		case <-timer.C:
+		}
+
+		interval = min(interval*2, maxInterval)
+	}
+}
+
+func (w *statusWaiter) timeoutError(ctx context.Context, status string, messages []string) error {
+	if status == "" {
+		return fmt.Errorf("%s did not reach status %s: %w", w.Name, strings.Join(w.Target, ", "), ctx.Err())
+	}
+	return fmt.Errorf("%s did not reach status %s, last status: %s%s: %w", w.Name, strings.Join(w.Target, ", "), status, formatStatusMessages(messages), ctx.Err())
+}
+
+// withJitter returns a random duration between half and the whole of d.
+func withJitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func formatStatusMessages(messages []string) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(messages, " ") + ")"
+}
+
+// waitForDeployment waits for the deployment to become healthy, and fails
+// early with the deployment status messages if it fails.
+//
+// A deployment superseded by a newer deployment of its service before being
+// seen healthy, whether it was stopped or canceled, is not a failure: the newer deployment is the one running the
+// service, and is waited for by whoever triggered it. A deployment stopped for
+// any other reason is reported as such.
+func waitForDeployment(ctx context.Context, client *koyeb.APIClient, deploymentId string, timeout time.Duration) error {
+	var deployment *koyeb.Deployment
+	w := &statusWaiter{
+		Name: fmt.Sprintf("deployment %s", deploymentId),
+		Refresh: func(ctx context.Context) (string, []string, *_nethttp.Response, error) {
+			res, resp, err := client.DeploymentsApi.GetDeployment(ctx, deploymentId).Execute()
+			if err != nil {
+				return "", nil, resp, err
+			}
+			deployment = res.Deployment
+			return string(deployment.GetStatus()), deployment.GetMessages(), resp, nil
+		},
+		Pending: deploymentPendingStatus,
+		Target:  append(slices.Clone(deploymentTargetStatus), deploymentStoppedStatus...),
+		Failure: deploymentFailureStatus,
+		Timeout: timeout,
+	}
+	if err := w.Wait(ctx); err != nil {
+		return err
+	}
+
+	status := string(deployment.GetStatus())
+	if !slices.Contains(deploymentStoppedStatus, status) {
+		return nil
+	}
+
+	res, _, err := client.ServicesApi.GetService(ctx, deployment.GetServiceId()).Execute()
+	if err != nil {
+		return fmt.Errorf("deployment %s was stopped, error retrieving service %s: %w", deploymentId, deployment.GetServiceId(), err)
+	}
+	if latest := res.Service.GetLatestDeploymentId(); latest != deploymentId {
+		log.Printf("[INFO] Deployment %s was superseded by deployment %s before becoming healthy", deploymentId, latest)
+		return nil
+	}
+	return fmt.Errorf("deployment %s was stopped with status %s before becoming healthy%s", deploymentId, status, formatStatusMessages(deployment.GetMessages()))
+}
+
+// waitForServicePaused waits for a service to be paused.
+func waitForServicePaused(ctx context.Context, client *koyeb.APIClient, serviceId string, timeout time.Duration) error {
+	w := &statusWaiter{
+		Name:    fmt.Sprintf("service %s", serviceId),
+		Refresh: serviceStatusFunc(client, serviceId),
+		Target:  []string{string(koyeb.SERVICESTATUS_PAUSED)},
+		Failure: []string{string(koyeb.SERVICESTATUS_DELETING), string(koyeb.SERVICESTATUS_DELETED)},
+		Timeout: timeout,
+	}
+	return w.Wait(ctx)
+}
+
+// waitForServiceResumed waits for a paused service to be healthy again.
+func waitForServiceResumed(ctx context.Context, client *koyeb.APIClient, serviceId string, timeout time.Duration) error {
+	w := &statusWaiter{
+		Name:    fmt.Sprintf("service %s", serviceId),
+		Refresh: serviceStatusFunc(client, serviceId),
+		Target:  []string{string(koyeb.SERVICESTATUS_HEALTHY)},
+		Failure: []string{
+			string(koyeb.SERVICESTATUS_UNHEALTHY),
+			string(koyeb.SERVICESTATUS_DELETING),
+			string(koyeb.SERVICESTATUS_DELETED),
+		},
+		Timeout: timeout,
+	}
+	return w.Wait(ctx)
+}
+
+// waitForDomain waits for a domain to be registered. A custom domain stays
+// pending until its DNS record points to its intended CNAME, which is up to
+// the user, so the domain is not waited for to be verified.
+func waitForDomain(ctx context.Context, client *koyeb.APIClient, domainId string, timeout time.Duration) error {
+	w := &statusWaiter{
+		Name:    fmt.Sprintf("domain %s", domainId),
+		Refresh: domainStatusFunc(client, domainId),
+		Target:  []string{string(koyeb.DOMAINSTATUS_PENDING), string(koyeb.DOMAINSTATUS_ACTIVE)},
+		Failure: []string{
+			string(koyeb.DOMAINSTATUS_ERROR),
+			string(koyeb.DOMAINSTATUS_DELETING),
+			string(koyeb.DOMAINSTATUS_DELETED),
+		},
+		Timeout: timeout,
+	}
+	return w.Wait(ctx)
+}
+
+// waitForVolume waits for a volume to be provisioned, attached or not.
+func waitForVolume(ctx context.Context, client *koyeb.APIClient, volumeId string, timeout time.Duration) error {
+	w := &statusWaiter{
+		Name:    fmt.Sprintf("volume %s", volumeId),
+		Refresh: volumeStatusFunc(client, volumeId),
+		Target: []string{
+			string(koyeb.PERSISTENTVOLUMESTATUS_DETACHED),
+			string(koyeb.PERSISTENTVOLUMESTATUS_ATTACHED),
+		},
+		Failure: []string{
+			string(koyeb.PERSISTENTVOLUMESTATUS_DELETING),
+			string(koyeb.PERSISTENTVOLUMESTATUS_DELETED),
+		},
+		Timeout: timeout,
+	}
+	return w.Wait(ctx)
+}
+
+func appStatusFunc(client *koyeb.APIClient, id string) statusFunc {
+	return func(ctx context.Context) (string, []string, *_nethttp.Response, error) {
+		res, resp, err := client.AppsApi.GetApp(ctx, id).Execute()
+		if err != nil {
+			return "", nil, resp, err
+		}
+		return string(res.App.GetStatus()), res.App.GetMessages(), resp, nil
+	}
+}
+
+func serviceStatusFunc(client *koyeb.APIClient, id string) statusFunc {
+	return func(ctx context.Context) (string, []string, *_nethttp.Response, error) {
+		res, resp, err := client.ServicesApi.GetService(ctx, id).Execute()
+		if err != nil {
+			return "", nil, resp, err
+		}
+		return string(res.Service.GetStatus()), res.Service.GetMessages(), resp, nil
+	}
+}
+
+func regionalDeploymentStatusFunc(client *koyeb.APIClient, id string) statusFunc {
+	return func(ctx context.Context) (string, []string, *_nethttp.Response, error) {
+		res, resp, err := client.RegionalDeploymentsApi.GetRegionalDeployment(ctx, id).Execute()
+		if err != nil {
+			return "", nil, resp, err
+		}
+		return string(res.RegionalDeployment.GetStatus()), res.RegionalDeployment.GetMessages(), resp, nil
+	}
+}
+
+func instanceStatusFunc(client *koyeb.APIClient, id string) statusFunc {
+	return func(ctx context.Context) (string, []string, *_nethttp.Response, error) {
+		res, resp, err := client.InstancesApi.GetInstance(ctx, id).Execute()
+		if err != nil {
+			return "", nil, resp, err
+		}
+		return string(res.Instance.GetStatus()), res.Instance.GetMessages(), resp, nil
+	}
+}
+
+func domainStatusFunc(client *koyeb.APIClient, id string) statusFunc {
+	return func(ctx context.Context) (string, []string, *_nethttp.Response, error) {
+		res, resp, err := client.DomainsApi.GetDomain(ctx, id).Execute()
+		if err != nil {
+			return "", nil, resp, err
+		}
+		return string(res.Domain.GetStatus()), res.Domain.GetMessages(), resp, nil
+	}
+}
+
+// volumeStatusFunc returns the status of a persistent volume. Volumes do not
+// expose status messages.
+func volumeStatusFunc(client *koyeb.APIClient, id string) statusFunc {
+	return func(ctx context.Context) (string, []string, *_nethttp.Response, error) {
+		res, resp, err := client.PersistentVolumesApi.GetPersistentVolume(ctx, id).Execute()
+		if err != nil {
+			return "", nil, resp, err
+		}
+		return string(res.Volume.GetStatus()), nil, resp, nil
+	}
+}
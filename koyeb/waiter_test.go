@@ -0,0 +1,249 @@
+package koyeb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+func newTestClient(url string) *koyeb.APIClient {
+	config := koyeb.NewConfiguration()
+	config.Servers[0].URL = url
+	return koyeb.NewAPIClient(config)
+}
+
+// statusSequence returns a statusFunc returning each status in turn, the last
+// one being repeated forever.
+func statusSequence(statuses ...string) (statusFunc, *int32) {
+	var calls int32
+	return func(ctx context.Context) (string, []string, *http.Response, error) {
+		i := int(atomic.AddInt32(&calls, 1)) - 1
+		if i >= len(statuses) {
+			i = len(statuses) - 1
+		}
+		return statuses[i], []string{fmt.Sprintf("message %d", i)}, nil, nil
+	}, &calls
+}
+
+func TestStatusWaiter_ReachesTarget(t *testing.T) {
+	refresh, calls := statusSequence("PENDING", "STARTING", "HEALTHY")
+
+	err := (&statusWaiter{
+		Name:        "deployment test",
+		Refresh:     refresh,
+		Pending:     []string{"PENDING", "STARTING"},
+		Target:      []string{"HEALTHY"},
+		Failure:     []string{"ERROR"},
+		MinInterval: time.Millisecond,
+		MaxInterval: 2 * time.Millisecond,
+	}).Wait(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if *calls != 3 {
+		t.Fatalf("expected 3 polls, got %d", *calls)
+	}
+}
+
+func TestStatusWaiter_FailsFast(t *testing.T) {
+	refresh, calls := statusSequence("PENDING", "ERROR", "HEALTHY")
+
+	err := (&statusWaiter{
+		Name:        "deployment test",
+		Refresh:     refresh,
+		Target:      []string{"HEALTHY"},
+		Failure:     []string{"ERROR"},
+		MinInterval: time.Millisecond,
+	}).Wait(context.Background())
+	if err == nil {
+		t.Fatal("expected an error for a failure status")
+	}
+	if !strings.Contains(err.Error(), "reached status ERROR (message 1)") {
+		t.Fatalf("expected the status messages in the error, got: %s", err)
+	}
+	if *calls != 2 {
+		t.Fatalf("expected 2 polls, got %d", *calls)
+	}
+}
+
+func TestStatusWaiter_UnexpectedStatus(t *testing.T) {
+	refresh, _ := statusSequence("PENDING", "PAUSED")
+
+	err := (&statusWaiter{
+		Name:        "service test",
+		Refresh:     refresh,
+		Pending:     []string{"PENDING"},
+		Target:      []string{"HEALTHY"},
+		MinInterval: time.Millisecond,
+	}).Wait(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "unexpected status PAUSED") {
+		t.Fatalf("expected an unexpected status error, got: %v", err)
+	}
+}
+
+func TestStatusWaiter_Timeout(t *testing.T) {
+	refresh, _ := statusSequence("STARTING")
+
+	start := time.Now()
+	err := (&statusWaiter{
+		Name:        "service test",
+		Refresh:     refresh,
+		Target:      []string{"HEALTHY"},
+		Timeout:     50 * time.Millisecond,
+		MinInterval: 10 * time.Millisecond,
+		MaxInterval: time.Hour,
+	}).Wait(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline exceeded error, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "last status: STARTING (message 0)") {
+		t.Fatalf("expected the last status in the error, got: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("waiter did not honor its timeout, took %s", elapsed)
+	}
+}
+
+func TestStatusWaiter_ContextCanceled(t *testing.T) {
+	refresh, _ := statusSequence("STARTING")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := (&statusWaiter{
+		Name:    "service test",
+		Refresh: refresh,
+		Target:  []string{"HEALTHY"},
+	}).Wait(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled error, got: %v", err)
+	}
+}
+
+func TestStatusWaiter_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"status": 404, "code": "not_found", "message": "Not found"}`)
+	}))
+	defer server.Close()
+	client := newTestClient(server.URL)
+
+	err := (&statusWaiter{
+		Name:             "app test",
+		Refresh:          appStatusFunc(client, "app-id"),
+		Target:           []string{"DELETED"},
+		NotFoundIsTarget: true,
+	}).Wait(context.Background())
+	if err != nil {
+		t.Fatalf("expected a missing app to reach the target, got: %s", err)
+	}
+
+	err = (&statusWaiter{
+		Name:    "app test",
+		Refresh: appStatusFunc(client, "app-id"),
+		Target:  []string{"HEALTHY"},
+	}).Wait(context.Background())
+	if err == nil {
+		t.Fatal("expected an error for a missing app")
+	}
+}
+
+func TestStatusWaiter_NoResponse(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	client := newTestClient(server.URL)
+	server.Close()
+
+	err := (&statusWaiter{
+		Name:             "volume test",
+		Refresh:          volumeStatusFunc(client, "volume-id"),
+		Target:           []string{"PERSISTENT_VOLUME_STATUS_DELETED"},
+		NotFoundIsTarget: true,
+	}).Wait(context.Background())
+	if err == nil {
+		t.Fatal("expected an error when the API is unreachable")
+	}
+}
+
+func TestWaitForDeployment_Failure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"deployment": {"id": "deployment-id", "status": "ERROR", "messages": ["Build failed", "exit code 1"]}}`)
+	}))
+	defer server.Close()
+
+	err := waitForDeployment(context.Background(), newTestClient(server.URL), "deployment-id", time.Minute)
+	if err == nil {
+		t.Fatal("expected an error for a deployment in error")
+	}
+	if !strings.Contains(err.Error(), "Build failed exit code 1") {
+		t.Fatalf("expected the deployment messages in the error, got: %s", err)
+	}
+}
+
+func TestWaitForDeployment_Healthy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"deployment": {"id": "deployment-id", "status": "HEALTHY"}}`)
+	}))
+	defer server.Close()
+
+	err := waitForDeployment(context.Background(), newTestClient(server.URL), "deployment-id", time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestWaitForDeployment_Stopped(t *testing.T) {
+	for _, test := range []struct {
+		name             string
+		status           string
+		latestDeployment string
+		err              string
+	}{
+		{name: "superseded", status: "STOPPED", latestDeployment: "newer-deployment-id"},
+		{name: "stashed", status: "STASHED", latestDeployment: "newer-deployment-id"},
+		{name: "stopped", status: "STOPPED", latestDeployment: "deployment-id", err: "deployment deployment-id was stopped with status STOPPED"},
+		{name: "canceled superseded", status: "CANCELED", latestDeployment: "newer-deployment-id"},
+		{name: "canceled", status: "CANCELED", latestDeployment: "deployment-id", err: "deployment deployment-id was stopped with status CANCELED"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/v1/deployments/deployment-id":
+					fmt.Fprintf(w, `{"deployment": {"id": "deployment-id", "service_id": "service-id", "status": %q}}`, test.status)
+				case "/v1/services/service-id":
+					fmt.Fprintf(w, `{"service": {"id": "service-id", "latest_deployment_id": %q}}`, test.latestDeployment)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			err := waitForDeployment(context.Background(), newTestClient(server.URL), "deployment-id", time.Minute)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("unexpected error: %s", err)
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Fatalf("expected an error containing %q, got: %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := withJitter(time.Second)
+		if d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("jittered interval out of bounds: %s", d)
+		}
+	}
+}
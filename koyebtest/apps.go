@@ -0,0 +1,193 @@
+package koyebtest
+
+import (
+	"net/http"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+type appEntry struct {
+	lifecycle
+	seq int64
+	app koyeb.App
+}
+
+// App returns the app with the given ID.
+func (s *Server) App(id string) (koyeb.App, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.apps[id]
+	if !ok {
+		return koyeb.App{}, false
+	}
+	return entry.app, true
+}
+
+func (s *Server) handleApps(w http.ResponseWriter, r *http.Request, id string, action string) {
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		s.listApps(w, r)
+	case id == "" && r.Method == http.MethodPost:
+		s.createApp(w, r)
+	case action == "" && r.Method == http.MethodGet:
+		s.getApp(w, id)
+	case action == "" && r.Method == http.MethodDelete:
+		s.deleteApp(w, id)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) createApp(w http.ResponseWriter, r *http.Request) {
+	var req koyeb.CreateApp
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	name := req.GetName()
+	if name == "" {
+		writeFieldError(w, "name", "is required")
+		return
+	}
+	for _, entry := range s.apps {
+		if entry.app.GetName() == name {
+			writeFieldError(w, "name", "already exists")
+			return
+		}
+	}
+
+	app := koyeb.App{
+		Id:             toOpt(newID()),
+		Name:           toOpt(name),
+		OrganizationId: toOpt(s.OrganizationID),
+		CreatedAt:      now(),
+		UpdatedAt:      now(),
+		Status:         koyeb.APPSTATUS_STARTING.Ptr(),
+		Messages:       []string{},
+		Version:        toOpt("1"),
+	}
+
+	// Every app gets a domain assigned automatically
+	domain := s.newDomain(name+"-"+s.OrganizationID[:8]+".koyeb.app", koyeb.DOMAINTYPE_AUTOASSIGNED, app.GetId())
+	domain.setStatus(koyeb.DOMAINSTATUS_ACTIVE)
+
+	entry := &appEntry{seq: s.nextSeq(), app: app}
+	entry.then(string(koyeb.APPSTATUS_HEALTHY))
+	s.apps[app.GetId()] = entry
+
+	writeJSON(w, http.StatusOK, koyeb.CreateAppReply{App: s.appWithDomains(entry)})
+}
+
+func (s *Server) getApp(w http.ResponseWriter, id string) {
+	entry, ok := s.apps[id]
+	if !ok {
+		notFound(w, "app")
+		return
+	}
+
+	if status, ok := entry.advance(); ok {
+		entry.app.Status = koyeb.AppStatus(status).Ptr()
+		if status == string(koyeb.APPSTATUS_DELETED) {
+			entry.app.TerminatedAt = now()
+			s.removeApp(id)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.GetAppReply{App: s.appWithDomains(entry)})
+}
+
+func (s *Server) listApps(w http.ResponseWriter, r *http.Request) {
+	entries := []*appEntry{}
+	for _, entry := range s.apps {
+		if matchFilter(r, "name", entry.app.GetName()) {
+			entries = append(entries, entry)
+		}
+	}
+
+	entries, p, ok := paginate(w, r, entries, func(e *appEntry) int64 { return e.seq })
+	if !ok {
+		return
+	}
+
+	apps := []koyeb.AppListItem{}
+	for _, entry := range entries {
+		app := s.appWithDomains(entry)
+		apps = append(apps, koyeb.AppListItem{
+			Id:             app.Id,
+			Name:           app.Name,
+			OrganizationId: app.OrganizationId,
+			CreatedAt:      app.CreatedAt,
+			UpdatedAt:      app.UpdatedAt,
+			Domains:        app.Domains,
+			Status:         app.Status,
+			Messages:       app.Messages,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.ListAppsReply{
+		Apps:    apps,
+		Limit:   toOpt(p.Limit),
+		Offset:  toOpt(p.Offset),
+		Count:   toOpt(p.Count),
+		HasNext: toOpt(p.HasNext),
+	})
+}
+
+func (s *Server) deleteApp(w http.ResponseWriter, id string) {
+	entry, ok := s.apps[id]
+	if !ok {
+		notFound(w, "app")
+		return
+	}
+
+	// Deleting an app deletes its services
+	for _, service := range s.services {
+		if service.service.GetAppId() == id {
+			s.markServiceDeleting(service)
+		}
+	}
+
+	entry.app.Status = koyeb.APPSTATUS_DELETING.Ptr()
+	entry.then(string(koyeb.APPSTATUS_DELETED))
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// removeApp removes the app and the objects attached to it.
+func (s *Server) removeApp(id string) {
+	for domainID, domain := range s.domains {
+		if domain.domain.GetAppId() != id {
+			continue
+		}
+		if domain.domain.GetType() == koyeb.DOMAINTYPE_AUTOASSIGNED {
+			delete(s.domains, domainID)
+		} else {
+			domain.domain.AppId = toOpt("")
+		}
+	}
+	for serviceID, service := range s.services {
+		if service.service.GetAppId() == id {
+			s.removeService(serviceID)
+		}
+	}
+	delete(s.apps, id)
+}
+
+// appWithDomains returns the app along with the domains assigned to it.
+func (s *Server) appWithDomains(entry *appEntry) *koyeb.App {
+	app := entry.app
+	app.Domains = []koyeb.Domain{}
+
+	domains := []*domainEntry{}
+	for _, domain := range s.domains {
+		if domain.domain.GetAppId() == app.GetId() {
+			domains = append(domains, domain)
+		}
+	}
+	sortBySeq(domains, func(e *domainEntry) int64 { return e.seq })
+	for _, domain := range domains {
+		app.Domains = append(app.Domains, domain.domain)
+	}
+	return &app
+}
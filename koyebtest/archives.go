@@ -0,0 +1,98 @@
+package koyebtest
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+// uploadPath is the path of the signed URLs where archives are uploaded. Like
+// on the real platform, uploads are not authenticated with the API token.
+const uploadPath = "/uploads/"
+
+type archiveEntry struct {
+	archive koyeb.Archive
+	content []byte
+}
+
+// ArchiveContent returns the content uploaded for the archive with the given
+// ID, and false if nothing was uploaded yet.
+func (s *Server) ArchiveContent(id string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.archives[id]
+	if !ok || entry.content == nil {
+		return nil, false
+	}
+	return entry.content, true
+}
+
+func (s *Server) handleArchives(w http.ResponseWriter, r *http.Request, id string, action string) {
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		s.createArchive(w, r)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) createArchive(w http.ResponseWriter, r *http.Request) {
+	var req koyeb.CreateArchive
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if size, err := strconv.ParseInt(req.GetSize(), 10, 64); err != nil || size <= 0 {
+		writeFieldError(w, "size", "must be greater than 0")
+		return
+	}
+
+	id := newID()
+	entry := &archiveEntry{
+		archive: koyeb.Archive{
+			Id:             toOpt(id),
+			OrganizationId: toOpt(s.OrganizationID),
+			UploadUrl:      toOpt(s.URL + uploadPath + id),
+			Size:           req.Size,
+			CreatedAt:      now(),
+		},
+	}
+	s.archives[id] = entry
+
+	writeJSON(w, http.StatusOK, koyeb.CreateArchiveReply{Archive: &entry.archive})
+}
+
+// uploadArchive stores the content uploaded to the signed URL of an archive,
+// which must have the size announced when the archive was created.
+func (s *Server) uploadArchive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.archives[strings.TrimPrefix(r.URL.Path, uploadPath)]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if strconv.Itoa(len(content)) != entry.archive.GetSize() {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	entry.content = content
+	w.WriteHeader(http.StatusOK)
+}
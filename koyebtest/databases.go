@@ -0,0 +1,98 @@
+package koyebtest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+const databasePort = 5432
+
+// validateDatabase validates the database source of a DATABASE service.
+func (s *Server) validateDatabase(w http.ResponseWriter, definition *koyeb.DeploymentDefinition) bool {
+	neon := definition.Database.NeonPostgres
+
+	switch {
+	case definition.GetType() != koyeb.DEPLOYMENTDEFINITIONTYPE_DATABASE:
+		writeFieldError(w, "definition.type", "must be DATABASE for database services")
+	case neon == nil:
+		writeFieldError(w, "definition.database", "a neon_postgres database is required")
+	case neon.GetPgVersion() < 14 || neon.GetPgVersion() > 17:
+		writeFieldError(w, "definition.database.neon_postgres.pg_version", "unsupported version")
+	case neon.GetRegion() == "":
+		writeFieldError(w, "definition.database.neon_postgres.region", "is required")
+	case len(neon.Roles) == 0:
+		writeFieldError(w, "definition.database.neon_postgres.roles", "at least one role is required")
+	case len(neon.Databases) == 0:
+		writeFieldError(w, "definition.database.neon_postgres.databases", "at least one database is required")
+	default:
+		roles := map[string]bool{}
+		for i, role := range neon.Roles {
+			if role.GetName() == "" || role.GetSecret() == "" {
+				writeFieldError(w, "definition.database.neon_postgres.roles."+strconv.Itoa(i), "name and secret are required")
+				return false
+			}
+			roles[role.GetName()] = true
+		}
+		for i, database := range neon.Databases {
+			if !roles[database.GetOwner()] {
+				writeFieldError(w, "definition.database.neon_postgres.databases."+strconv.Itoa(i)+".owner", "role not found")
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// databaseInfo provisions the roles of a database deployment, storing their
+// passwords in managed secrets, and returns the connection information.
+func (s *Server) databaseInfo(service *serviceEntry, definition *koyeb.DeploymentDefinition) *koyeb.DeploymentDatabaseInfo {
+	neon := definition.Database.GetNeonPostgres()
+
+	info := &koyeb.DeploymentNeonPostgresDatabaseInfo{
+		ServerHost: toOpt("ep-" + service.service.GetId()[:8] + "." + neon.GetRegion() + ".pg.koyeb.app"),
+		ServerPort: toOpt(int64(databasePort)),
+		Roles:      []koyeb.DeploymentNeonPostgresDatabaseInfoRole{},
+	}
+
+	for _, role := range neon.Roles {
+		secret := s.databaseRoleSecret(role)
+		info.Roles = append(info.Roles, koyeb.DeploymentNeonPostgresDatabaseInfoRole{
+			Name:     role.Name,
+			SecretId: secret.Id,
+		})
+	}
+
+	return &koyeb.DeploymentDatabaseInfo{NeonPostgres: info}
+}
+
+// databaseRoleSecret returns the managed secret holding the password of the
+// role, creating it with a random password the first time.
+func (s *Server) databaseRoleSecret(role koyeb.NeonPostgresDatabaseNeonRole) koyeb.Secret {
+	for _, entry := range s.secrets {
+		if entry.secret.GetName() == role.GetSecret() {
+			return entry.secret
+		}
+	}
+
+	entry := &secretEntry{
+		seq: s.nextSeq(),
+		secret: koyeb.Secret{
+			Id:             toOpt(newID()),
+			Name:           role.Secret,
+			OrganizationId: toOpt(s.OrganizationID),
+			Type:           koyeb.SECRETTYPE_MANAGED.Ptr(),
+			CreatedAt:      now(),
+			UpdatedAt:      now(),
+			DatabaseRolePassword: &koyeb.DatabaseRolePassword{
+				Username: role.Name,
+				Password: toOpt(uuid.NewString()),
+			},
+		},
+	}
+	s.secrets[entry.secret.GetId()] = entry
+	return entry.secret
+}
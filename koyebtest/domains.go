@@ -0,0 +1,180 @@
+package koyebtest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+type domainEntry struct {
+	lifecycle
+	seq    int64
+	domain koyeb.Domain
+}
+
+func (s *Server) handleDomains(w http.ResponseWriter, r *http.Request, id string, action string) {
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		s.listDomains(w, r)
+	case id == "" && r.Method == http.MethodPost:
+		s.createDomain(w, r)
+	case action == "" && r.Method == http.MethodGet:
+		s.getDomain(w, id)
+	case action == "" && r.Method == http.MethodPatch:
+		s.updateDomain(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		s.deleteDomain(w, id)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// newDomain stores a new domain, assigned to the app appID if not empty.
+func (s *Server) newDomain(name string, domainType koyeb.DomainType, appID string) *domainEntry {
+	id := newID()
+	entry := &domainEntry{
+		seq: s.nextSeq(),
+		domain: koyeb.Domain{
+			Id:              toOpt(id),
+			OrganizationId:  toOpt(s.OrganizationID),
+			Name:            toOpt(name),
+			CreatedAt:       now(),
+			UpdatedAt:       now(),
+			Type:            domainType.Ptr(),
+			AppId:           toOpt(appID),
+			DeploymentGroup: toOpt("prod"),
+			IntendedCname:   toOpt(id[:8] + ".cname.koyeb.app"),
+			Version:         toOpt("1"),
+		},
+	}
+	entry.setStatus(koyeb.DOMAINSTATUS_PENDING)
+	s.domains[id] = entry
+	return entry
+}
+
+// setStatus sets the status of the domain and its status messages, which are
+// never empty, as with the Koyeb API.
+func (entry *domainEntry) setStatus(status koyeb.DomainStatus) {
+	entry.domain.Status = status.Ptr()
+	switch status {
+	case koyeb.DOMAINSTATUS_PENDING:
+		entry.domain.Messages = []string{"Waiting for the domain to point to " + entry.domain.GetIntendedCname()}
+	case koyeb.DOMAINSTATUS_ACTIVE:
+		entry.domain.VerifiedAt = now()
+		entry.domain.Messages = []string{"Domain is active"}
+	default:
+		entry.domain.Messages = []string{"Domain is " + strings.ToLower(string(status))}
+	}
+}
+
+func (s *Server) createDomain(w http.ResponseWriter, r *http.Request) {
+	var req koyeb.CreateDomain
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	name := req.GetName()
+	if name == "" || !strings.Contains(name, ".") {
+		writeFieldError(w, "name", "must be a valid domain name")
+		return
+	}
+	for _, entry := range s.domains {
+		if entry.domain.GetName() == name {
+			writeFieldError(w, "name", "already exists")
+			return
+		}
+	}
+	if req.GetAppId() != "" {
+		if _, ok := s.apps[req.GetAppId()]; !ok {
+			writeFieldError(w, "app_id", "app not found")
+			return
+		}
+	}
+
+	entry := s.newDomain(name, koyeb.DOMAINTYPE_CUSTOM, req.GetAppId())
+	entry.then(string(koyeb.DOMAINSTATUS_ACTIVE))
+
+	writeJSON(w, http.StatusOK, koyeb.CreateDomainReply{Domain: &entry.domain})
+}
+
+func (s *Server) getDomain(w http.ResponseWriter, id string) {
+	entry, ok := s.domains[id]
+	if !ok {
+		notFound(w, "domain")
+		return
+	}
+
+	if status, ok := entry.advance(); ok {
+		entry.setStatus(koyeb.DomainStatus(status))
+		if status == string(koyeb.DOMAINSTATUS_DELETED) {
+			delete(s.domains, id)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.GetDomainReply{Domain: &entry.domain})
+}
+
+func (s *Server) listDomains(w http.ResponseWriter, r *http.Request) {
+	entries := []*domainEntry{}
+	for _, entry := range s.domains {
+		if matchFilter(r, "name", entry.domain.GetName()) {
+			entries = append(entries, entry)
+		}
+	}
+
+	entries, p, ok := paginate(w, r, entries, func(e *domainEntry) int64 { return e.seq })
+	if !ok {
+		return
+	}
+
+	domains := []koyeb.Domain{}
+	for _, entry := range entries {
+		domains = append(domains, entry.domain)
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.ListDomainsReply{
+		Domains: domains,
+		Limit:   toOpt(p.Limit),
+		Offset:  toOpt(p.Offset),
+		Count:   toOpt(p.Count),
+	})
+}
+
+func (s *Server) updateDomain(w http.ResponseWriter, r *http.Request, id string) {
+	entry, ok := s.domains[id]
+	if !ok {
+		notFound(w, "domain")
+		return
+	}
+
+	var req koyeb.UpdateDomain
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.AppId != nil {
+		if req.GetAppId() != "" {
+			if _, ok := s.apps[req.GetAppId()]; !ok {
+				writeFieldError(w, "app_id", "app not found")
+				return
+			}
+		}
+		entry.domain.AppId = req.AppId
+	}
+	entry.domain.UpdatedAt = now()
+
+	writeJSON(w, http.StatusOK, koyeb.UpdateDomainReply{Domain: &entry.domain})
+}
+
+func (s *Server) deleteDomain(w http.ResponseWriter, id string) {
+	entry, ok := s.domains[id]
+	if !ok {
+		notFound(w, "domain")
+		return
+	}
+
+	entry.setStatus(koyeb.DOMAINSTATUS_DELETING)
+	entry.then(string(koyeb.DOMAINSTATUS_DELETED))
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
@@ -0,0 +1,339 @@
+package koyebtest
+
+import (
+	"net/http"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+	"golang.org/x/exp/slices"
+)
+
+type regionalDeploymentEntry struct {
+	seq                int64
+	regionalDeployment koyeb.RegionalDeployment
+}
+
+type instanceEntry struct {
+	seq      int64
+	instance koyeb.Instance
+}
+
+// Instances returns the instances of the service, in creation order.
+func (s *Server) Instances(serviceID string) []koyeb.Instance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := []*instanceEntry{}
+	for _, entry := range s.instances {
+		if entry.instance.GetServiceId() == serviceID {
+			entries = append(entries, entry)
+		}
+	}
+	sortBySeq(entries, func(e *instanceEntry) int64 { return e.seq })
+
+	instances := []koyeb.Instance{}
+	for _, entry := range entries {
+		instances = append(instances, entry.instance)
+	}
+	return instances
+}
+
+// RestartInstance simulates a crash of the instance: it moves to the ERROR
+// status and a new instance with the same replica index replaces it.
+func (s *Server) RestartInstance(id string) (koyeb.Instance, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.instances[id]
+	if !ok || entry.instance.GetStatus() == koyeb.INSTANCESTATUS_STOPPED {
+		return koyeb.Instance{}, false
+	}
+	entry.instance.Status = koyeb.INSTANCESTATUS_ERROR.Ptr()
+	entry.instance.TerminatedAt = now()
+	entry.instance.Messages = []string{"Instance exited with code 1"}
+
+	regionalDeployment := s.regionalDeployments[entry.instance.GetRegionalDeploymentId()]
+	replacement := s.newInstance(regionalDeployment, entry.instance.GetReplicaIndex())
+	return replacement.instance, true
+}
+
+func (s *Server) handleRegionalDeployments(w http.ResponseWriter, r *http.Request, id string, action string) {
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		s.listRegionalDeployments(w, r)
+	case action == "" && r.Method == http.MethodGet:
+		s.getRegionalDeployment(w, id)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) handleInstances(w http.ResponseWriter, r *http.Request, id string, action string) {
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		s.listInstances(w, r)
+	case action == "" && r.Method == http.MethodGet:
+		s.getInstance(w, id)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// newRegionalDeployments creates a regional deployment in each region of the
+// deployment.
+func (s *Server) newRegionalDeployments(deployment *koyeb.Deployment) {
+	for _, region := range deployment.Definition.Regions {
+		entry := &regionalDeploymentEntry{
+			seq: s.nextSeq(),
+			regionalDeployment: koyeb.RegionalDeployment{
+				Id:              toOpt(newID()),
+				CreatedAt:       now(),
+				UpdatedAt:       now(),
+				OrganizationId:  deployment.OrganizationId,
+				AppId:           deployment.AppId,
+				ServiceId:       deployment.ServiceId,
+				DeploymentId:    deployment.Id,
+				Region:          toOpt(region),
+				Status:          koyeb.REGIONALDEPLOYMENTSTATUS_PENDING.Ptr(),
+				Messages:        []string{},
+				Datacenters:     []string{region + "1"},
+				Version:         deployment.Version,
+				DeploymentGroup: deployment.DeploymentGroup,
+			},
+		}
+		s.regionalDeployments[entry.regionalDeployment.GetId()] = entry
+	}
+}
+
+// setRegionalDeploymentsStatus follows the status of the deployment: its
+// instances are started when it becomes healthy, and stopped when it stops.
+func (s *Server) setRegionalDeploymentsStatus(deployment *koyeb.Deployment) {
+	if deployment.Definition.Database != nil {
+		return
+	}
+
+	for _, entry := range s.regionalDeployments {
+		if entry.regionalDeployment.GetDeploymentId() != deployment.GetId() {
+			continue
+		}
+
+		switch deployment.GetStatus() {
+		case koyeb.DEPLOYMENTSTATUS_HEALTHY:
+			s.startInstances(entry, deployment.Definition)
+		case koyeb.DEPLOYMENTSTATUS_STASHED, koyeb.DEPLOYMENTSTATUS_STOPPED:
+			s.stopInstances(entry, koyeb.REGIONALDEPLOYMENTSTATUS_STOPPED)
+		case koyeb.DEPLOYMENTSTATUS_CANCELED:
+			s.stopInstances(entry, koyeb.REGIONALDEPLOYMENTSTATUS_CANCELED)
+		case koyeb.DEPLOYMENTSTATUS_ERROR:
+			s.stopInstances(entry, koyeb.REGIONALDEPLOYMENTSTATUS_ERROR)
+		default:
+			entry.regionalDeployment.Status = koyeb.RegionalDeploymentStatus(deployment.GetStatus()).Ptr()
+			entry.regionalDeployment.UpdatedAt = now()
+		}
+	}
+}
+
+// startInstances starts the minimum number of instances of the scaling of the
+// region. Regional deployments scaled to zero are sleeping.
+func (s *Server) startInstances(entry *regionalDeploymentEntry, definition *koyeb.DeploymentDefinition) {
+	count := int64(1)
+	for _, scaling := range definition.Scalings {
+		if slices.Contains(scaling.Scopes, "region:"+entry.regionalDeployment.GetRegion()) {
+			count = scaling.GetMin()
+		}
+	}
+
+	for i := int64(0); i < count; i++ {
+		s.newInstance(entry, i)
+	}
+
+	status := koyeb.REGIONALDEPLOYMENTSTATUS_HEALTHY
+	if count == 0 {
+		status = koyeb.REGIONALDEPLOYMENTSTATUS_SLEEPING
+	}
+	entry.regionalDeployment.Status = status.Ptr()
+	entry.regionalDeployment.UpdatedAt = now()
+	entry.regionalDeployment.StartedAt = now()
+}
+
+// stopInstances stops the running instances of the regional deployment.
+func (s *Server) stopInstances(entry *regionalDeploymentEntry, status koyeb.RegionalDeploymentStatus) {
+	for _, instance := range s.instances {
+		if instance.instance.GetRegionalDeploymentId() == entry.regionalDeployment.GetId() && instance.instance.GetStatus() == koyeb.INSTANCESTATUS_HEALTHY {
+			instance.instance.Status = koyeb.INSTANCESTATUS_STOPPED.Ptr()
+			instance.instance.UpdatedAt = now()
+			instance.instance.TerminatedAt = now()
+		}
+	}
+
+	entry.regionalDeployment.Status = status.Ptr()
+	entry.regionalDeployment.UpdatedAt = now()
+	entry.regionalDeployment.TerminatedAt = now()
+}
+
+// pauseInstances stops the instances of the active deployment of a paused
+// service, and starts them again when the service is resumed.
+func (s *Server) pauseInstances(service *serviceEntry, paused bool) {
+	active, ok := s.deployments[service.service.GetActiveDeploymentId()]
+	if !ok || active.deployment.Definition.Database != nil {
+		return
+	}
+
+	for _, entry := range s.regionalDeployments {
+		if entry.regionalDeployment.GetDeploymentId() != active.deployment.GetId() {
+			continue
+		}
+		if paused {
+			s.stopInstances(entry, koyeb.REGIONALDEPLOYMENTSTATUS_STOPPED)
+		} else {
+			s.startInstances(entry, active.deployment.Definition)
+		}
+	}
+}
+
+func (s *Server) newInstance(regionalDeployment *regionalDeploymentEntry, replicaIndex int64) *instanceEntry {
+	rd := regionalDeployment.regionalDeployment
+	entry := &instanceEntry{
+		seq: s.nextSeq(),
+		instance: koyeb.Instance{
+			Id:                   toOpt(newID()),
+			CreatedAt:            now(),
+			UpdatedAt:            now(),
+			OrganizationId:       rd.OrganizationId,
+			AppId:                rd.AppId,
+			ServiceId:            rd.ServiceId,
+			RegionalDeploymentId: rd.Id,
+			AllocationId:         toOpt(newID()),
+			ReplicaIndex:         toOpt(replicaIndex),
+			Region:               rd.Region,
+			Datacenter:           toOpt(rd.Datacenters[0]),
+			Status:               koyeb.INSTANCESTATUS_HEALTHY.Ptr(),
+			Messages:             []string{},
+			StartedAt:            now(),
+			SucceededAt:          now(),
+			XyzDeploymentId:      rd.DeploymentId,
+		},
+	}
+	s.instances[entry.instance.GetId()] = entry
+	return entry
+}
+
+// removeInstances removes the regional deployments and the instances of the
+// service.
+func (s *Server) removeInstances(serviceID string) {
+	for id, entry := range s.regionalDeployments {
+		if entry.regionalDeployment.GetServiceId() == serviceID {
+			delete(s.regionalDeployments, id)
+		}
+	}
+	for id, entry := range s.instances {
+		if entry.instance.GetServiceId() == serviceID {
+			delete(s.instances, id)
+		}
+	}
+}
+
+func (s *Server) getRegionalDeployment(w http.ResponseWriter, id string) {
+	entry, ok := s.regionalDeployments[id]
+	if !ok {
+		notFound(w, "regional deployment")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.GetRegionalDeploymentReply{RegionalDeployment: &entry.regionalDeployment})
+}
+
+func (s *Server) listRegionalDeployments(w http.ResponseWriter, r *http.Request) {
+	entries := []*regionalDeploymentEntry{}
+	for _, entry := range s.regionalDeployments {
+		if matchFilter(r, "deployment_id", entry.regionalDeployment.GetDeploymentId()) {
+			entries = append(entries, entry)
+		}
+	}
+
+	entries, p, ok := paginate(w, r, entries, func(e *regionalDeploymentEntry) int64 { return e.seq })
+	if !ok {
+		return
+	}
+
+	regionalDeployments := []koyeb.RegionalDeploymentListItem{}
+	for _, entry := range entries {
+		rd := entry.regionalDeployment
+		regionalDeployments = append(regionalDeployments, koyeb.RegionalDeploymentListItem{
+			Id:         rd.Id,
+			CreatedAt:  rd.CreatedAt,
+			UpdatedAt:  rd.UpdatedAt,
+			Region:     rd.Region,
+			Status:     rd.Status,
+			Messages:   rd.Messages,
+			Definition: rd.Definition,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.ListRegionalDeploymentsReply{
+		RegionalDeployments: regionalDeployments,
+		Limit:               toOpt(p.Limit),
+		Offset:              toOpt(p.Offset),
+		Count:               toOpt(p.Count),
+		HasNext:             toOpt(p.HasNext),
+	})
+}
+
+func (s *Server) getInstance(w http.ResponseWriter, id string) {
+	entry, ok := s.instances[id]
+	if !ok {
+		notFound(w, "instance")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.GetInstanceReply{Instance: &entry.instance})
+}
+
+func (s *Server) listInstances(w http.ResponseWriter, r *http.Request) {
+	statuses := r.URL.Query()["statuses"]
+
+	entries := []*instanceEntry{}
+	for _, entry := range s.instances {
+		instance := entry.instance
+		if matchFilter(r, "app_id", instance.GetAppId()) &&
+			matchFilter(r, "service_id", instance.GetServiceId()) &&
+			matchFilter(r, "deployment_id", instance.GetXyzDeploymentId()) &&
+			matchFilter(r, "regional_deployment_id", instance.GetRegionalDeploymentId()) &&
+			(len(statuses) == 0 || slices.Contains(statuses, string(instance.GetStatus()))) {
+			entries = append(entries, entry)
+		}
+	}
+
+	entries, p, ok := paginate(w, r, entries, func(e *instanceEntry) int64 { return e.seq })
+	if !ok {
+		return
+	}
+
+	instances := []koyeb.InstanceListItem{}
+	for _, entry := range entries {
+		instance := entry.instance
+		instances = append(instances, koyeb.InstanceListItem{
+			Id:                   instance.Id,
+			CreatedAt:            instance.CreatedAt,
+			UpdatedAt:            instance.UpdatedAt,
+			OrganizationId:       instance.OrganizationId,
+			AppId:                instance.AppId,
+			ServiceId:            instance.ServiceId,
+			RegionalDeploymentId: instance.RegionalDeploymentId,
+			AllocationId:         instance.AllocationId,
+			Type:                 instance.Type,
+			ReplicaIndex:         instance.ReplicaIndex,
+			Region:               instance.Region,
+			Datacenter:           instance.Datacenter,
+			Status:               instance.Status,
+			Messages:             instance.Messages,
+			XyzDeploymentId:      instance.XyzDeploymentId,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.ListInstancesReply{
+		Instances: instances,
+		Limit:     toOpt(p.Limit),
+		Offset:    toOpt(p.Offset),
+		Count:     toOpt(p.Count),
+	})
+}
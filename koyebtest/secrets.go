@@ -0,0 +1,224 @@
+package koyebtest
+
+import (
+	"net/http"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+const redacted = "*****"
+
+type secretEntry struct {
+	seq    int64
+	secret koyeb.Secret
+}
+
+// Secret returns the secret with the given ID.
+func (s *Server) Secret(id string) (koyeb.Secret, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.secrets[id]
+	if !ok {
+		return koyeb.Secret{}, false
+	}
+	return entry.secret, true
+}
+
+func (s *Server) handleSecrets(w http.ResponseWriter, r *http.Request, id string, action string) {
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		s.listSecrets(w, r)
+	case id == "" && r.Method == http.MethodPost:
+		s.createSecret(w, r)
+	case action == "" && r.Method == http.MethodGet:
+		s.getSecret(w, id)
+	case action == "" && (r.Method == http.MethodPut || r.Method == http.MethodPatch):
+		s.updateSecret(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		s.deleteSecret(w, id)
+	case action == "reveal" && r.Method == http.MethodPost:
+		s.revealSecret(w, id)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) createSecret(w http.ResponseWriter, r *http.Request) {
+	var req koyeb.CreateSecret
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	name := req.GetName()
+	if name == "" {
+		writeFieldError(w, "name", "is required")
+		return
+	}
+	for _, entry := range s.secrets {
+		if entry.secret.GetName() == name {
+			writeFieldError(w, "name", "already exists")
+			return
+		}
+	}
+
+	secretType := req.GetType()
+	if secretType == "" {
+		secretType = koyeb.SECRETTYPE_SIMPLE
+	}
+
+	entry := &secretEntry{
+		seq: s.nextSeq(),
+		secret: koyeb.Secret{
+			Id:                     toOpt(newID()),
+			Name:                   toOpt(name),
+			OrganizationId:         toOpt(s.OrganizationID),
+			Type:                   secretType.Ptr(),
+			CreatedAt:              now(),
+			UpdatedAt:              now(),
+			Value:                  req.Value,
+			DockerHubRegistry:      req.DockerHubRegistry,
+			PrivateRegistry:        req.PrivateRegistry,
+			DigitalOceanRegistry:   req.DigitalOceanRegistry,
+			GithubRegistry:         req.GithubRegistry,
+			GitlabRegistry:         req.GitlabRegistry,
+			GcpContainerRegistry:   req.GcpContainerRegistry,
+			AzureContainerRegistry: req.AzureContainerRegistry,
+		},
+	}
+	s.secrets[entry.secret.GetId()] = entry
+
+	writeJSON(w, http.StatusOK, koyeb.CreateSecretReply{Secret: redactSecret(entry.secret)})
+}
+
+func (s *Server) getSecret(w http.ResponseWriter, id string) {
+	entry, ok := s.secrets[id]
+	if !ok {
+		notFound(w, "secret")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.GetSecretReply{Secret: redactSecret(entry.secret)})
+}
+
+func (s *Server) listSecrets(w http.ResponseWriter, r *http.Request) {
+	entries := []*secretEntry{}
+	for _, entry := range s.secrets {
+		if matchFilter(r, "name", entry.secret.GetName()) {
+			entries = append(entries, entry)
+		}
+	}
+
+	entries, p, ok := paginate(w, r, entries, func(e *secretEntry) int64 { return e.seq })
+	if !ok {
+		return
+	}
+
+	secrets := []koyeb.Secret{}
+	for _, entry := range entries {
+		secrets = append(secrets, *redactSecret(entry.secret))
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.ListSecretsReply{
+		Secrets: secrets,
+		Limit:   toOpt(p.Limit),
+		Offset:  toOpt(p.Offset),
+		Count:   toOpt(p.Count),
+	})
+}
+
+func (s *Server) updateSecret(w http.ResponseWriter, r *http.Request, id string) {
+	entry, ok := s.secrets[id]
+	if !ok {
+		notFound(w, "secret")
+		return
+	}
+
+	var req koyeb.Secret
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.Name != nil {
+		entry.secret.Name = req.Name
+	}
+	if req.Value != nil {
+		entry.secret.Value = req.Value
+	}
+	if req.DockerHubRegistry != nil || req.PrivateRegistry != nil || req.DigitalOceanRegistry != nil ||
+		req.GithubRegistry != nil || req.GitlabRegistry != nil || req.GcpContainerRegistry != nil ||
+		req.AzureContainerRegistry != nil {
+		entry.secret.DockerHubRegistry = req.DockerHubRegistry
+		entry.secret.PrivateRegistry = req.PrivateRegistry
+		entry.secret.DigitalOceanRegistry = req.DigitalOceanRegistry
+		entry.secret.GithubRegistry = req.GithubRegistry
+		entry.secret.GitlabRegistry = req.GitlabRegistry
+		entry.secret.GcpContainerRegistry = req.GcpContainerRegistry
+		entry.secret.AzureContainerRegistry = req.AzureContainerRegistry
+	}
+	entry.secret.UpdatedAt = now()
+
+	writeJSON(w, http.StatusOK, koyeb.UpdateSecretReply{Secret: redactSecret(entry.secret)})
+}
+
+func (s *Server) deleteSecret(w http.ResponseWriter, id string) {
+	if _, ok := s.secrets[id]; !ok {
+		notFound(w, "secret")
+		return
+	}
+	delete(s.secrets, id)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// revealSecret returns the value of a simple secret, the configuration of a
+// registry secret, or the credentials of a database role.
+func (s *Server) revealSecret(w http.ResponseWriter, id string) {
+	entry, ok := s.secrets[id]
+	if !ok {
+		notFound(w, "secret")
+		return
+	}
+
+	secret := entry.secret
+	var value interface{} = secret.GetValue()
+	switch {
+	case secret.DockerHubRegistry != nil:
+		value = secret.DockerHubRegistry
+	case secret.PrivateRegistry != nil:
+		value = secret.PrivateRegistry
+	case secret.DigitalOceanRegistry != nil:
+		value = secret.DigitalOceanRegistry
+	case secret.GithubRegistry != nil:
+		value = secret.GithubRegistry
+	case secret.GitlabRegistry != nil:
+		value = secret.GitlabRegistry
+	case secret.GcpContainerRegistry != nil:
+		value = secret.GcpContainerRegistry
+	case secret.AzureContainerRegistry != nil:
+		value = secret.AzureContainerRegistry
+	case secret.DatabaseRolePassword != nil:
+		value = secret.DatabaseRolePassword
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"value": value})
+}
+
+// redactSecret returns the secret with its value hidden. The value is only
+// returned when the secret is revealed.
+func redactSecret(secret koyeb.Secret) *koyeb.Secret {
+	if secret.Value != nil {
+		secret.Value = toOpt(redacted)
+	}
+	if secret.PrivateRegistry != nil {
+		registry := *secret.PrivateRegistry
+		registry.Password = toOpt(redacted)
+		secret.PrivateRegistry = &registry
+	}
+	if secret.DatabaseRolePassword != nil {
+		role := *secret.DatabaseRolePassword
+		role.Password = toOpt(redacted)
+		secret.DatabaseRolePassword = &role
+	}
+	return &secret
+}
@@ -0,0 +1,287 @@
+// Package koyebtest provides an in-memory fake of the Koyeb API, to run the
+// provider acceptance tests without network access or a Koyeb account.
+//
+// The fake implements the subset of the API used by the provider: apps,
+// services, deployments, regional deployments, instances, domains, secrets,
+// persistent volumes and archives.
+// Objects go through the same statuses as on the real platform, advancing by
+// one status each time they are retrieved, and deleted objects return 404 once
+// their deletion is complete.
+//
+//	server := koyebtest.NewServer()
+//	defer server.Close()
+//
+//	os.Setenv("KOYEB_API_URL", server.URL)
+//	os.Setenv("KOYEB_TOKEN", server.Token)
+package koyebtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+// Server is a fake Koyeb API server.
+type Server struct {
+	// URL of the server, to use as the provider API URL.
+	URL string
+	// Token is the only API token accepted by the server.
+	Token string
+	// OrganizationID is the ID of the organization owning every object.
+	OrganizationID string
+
+	server *httptest.Server
+
+	mu                  sync.Mutex
+	seq                 int64
+	apps                map[string]*appEntry
+	services            map[string]*serviceEntry
+	deployments         map[string]*deploymentEntry
+	regionalDeployments map[string]*regionalDeploymentEntry
+	instances           map[string]*instanceEntry
+	domains             map[string]*domainEntry
+	secrets             map[string]*secretEntry
+	volumes             map[string]*volumeEntry
+	archives            map[string]*archiveEntry
+}
+
+// NewServer starts a fake Koyeb API server with no objects. The caller must
+// call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		Token:               "koyebtest-" + uuid.NewString(),
+		OrganizationID:      uuid.NewString(),
+		apps:                make(map[string]*appEntry),
+		services:            make(map[string]*serviceEntry),
+		deployments:         make(map[string]*deploymentEntry),
+		regionalDeployments: make(map[string]*regionalDeploymentEntry),
+		instances:           make(map[string]*instanceEntry),
+		domains:             make(map[string]*domainEntry),
+		secrets:             make(map[string]*secretEntry),
+		volumes:             make(map[string]*volumeEntry),
+		archives:            make(map[string]*archiveEntry),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request, id string, action string)
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, uploadPath) {
+		s.uploadArchive(w, r)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.Token {
+		writeError(w, http.StatusUnauthorized, "unauthenticated", "Invalid token")
+		return
+	}
+
+	// Paths have the form /v1/<collection>[/<id>[/<action>]]
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 4 || parts[0] != "v1" {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return
+	}
+
+	var id, action string
+	if len(parts) > 2 {
+		id = parts[2]
+	}
+	if len(parts) > 3 {
+		action = parts[3]
+	}
+
+	handlers := map[string]handlerFunc{
+		"apps":                 s.handleApps,
+		"services":             s.handleServices,
+		"deployments":          s.handleDeployments,
+		"regional_deployments": s.handleRegionalDeployments,
+		"instances":            s.handleInstances,
+		"domains":              s.handleDomains,
+		"secrets":              s.handleSecrets,
+		"volumes":              s.handleVolumes,
+		"archives":             s.handleArchives,
+		"organizations":        s.handleOrganizations,
+	}
+	handler, ok := handlers[parts[1]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	handler(w, r, id, action)
+}
+
+// handleOrganizations only implements the organization switch, which returns
+// the server token since every object belongs to the same organization.
+func (s *Server) handleOrganizations(w http.ResponseWriter, r *http.Request, id string, action string) {
+	if r.Method != http.MethodPost || action != "switch" {
+		methodNotAllowed(w)
+		return
+	}
+	if id != s.OrganizationID {
+		notFound(w, "organization")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.LoginReply{Token: &koyeb.Token{
+		Id:             toOpt(s.Token),
+		OrganizationId: toOpt(s.OrganizationID),
+	}})
+}
+
+// nextSeq returns a sequence number used to list objects in creation order.
+func (s *Server) nextSeq() int64 {
+	s.seq++
+	return s.seq
+}
+
+// lifecycle holds the statuses an object goes through. The object moves to
+// the next status each time it is retrieved.
+type lifecycle struct {
+	next []string
+}
+
+func (l *lifecycle) then(statuses ...string) {
+	l.next = statuses
+}
+
+// advance returns the next status of the object, if any.
+func (l *lifecycle) advance() (string, bool) {
+	if len(l.next) == 0 {
+		return "", false
+	}
+	status := l.next[0]
+	l.next = l.next[1:]
+	return status, true
+}
+
+func newID() string {
+	return uuid.NewString()
+}
+
+func now() *time.Time {
+	t := time.Now().UTC()
+	return &t
+}
+
+func toOpt[T any](v T) *T {
+	return &v
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, koyeb.Error{
+		Status:  toOpt(int32(status)),
+		Code:    toOpt(code),
+		Message: toOpt(message),
+	})
+}
+
+// writeFieldError writes a validation error on a single field of the request.
+func writeFieldError(w http.ResponseWriter, field string, description string) {
+	writeJSON(w, http.StatusBadRequest, koyeb.ErrorWithFields{
+		Status:  toOpt(int32(http.StatusBadRequest)),
+		Code:    toOpt("invalid_argument"),
+		Message: toOpt("Validation error"),
+		Fields: []koyeb.ErrorField{
+			{Field: toOpt(field), Description: toOpt(description)},
+		},
+	})
+}
+
+func notFound(w http.ResponseWriter, kind string) {
+	writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("%s not found", kind))
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+}
+
+// decodeBody decodes the JSON body of the request into v, and writes an error
+// if the body is invalid.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_argument", fmt.Sprintf("Invalid body: %s", err))
+		return false
+	}
+	return true
+}
+
+type page struct {
+	Limit   int64
+	Offset  int64
+	Count   int64
+	HasNext bool
+}
+
+// paginate sorts items by creation order and returns the page selected by the
+// limit and offset query parameters.
+func paginate[T any](w http.ResponseWriter, r *http.Request, items []T, seq func(T) int64) ([]T, page, bool) {
+	limit, offset := int64(defaultLimit), int64(0)
+
+	for name, dest := range map[string]*int64{"limit": &limit, "offset": &offset} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			writeFieldError(w, name, "must be a positive integer")
+			return nil, page{}, false
+		}
+		*dest = n
+	}
+	if limit == 0 || limit > maxLimit {
+		limit = maxLimit
+	}
+
+	sortBySeq(items, seq)
+
+	count := int64(len(items))
+	start := min(offset, count)
+	end := min(offset+limit, count)
+
+	return items[start:end], page{Limit: limit, Offset: offset, Count: count, HasNext: end < count}, true
+}
+
+func sortBySeq[T any](items []T, seq func(T) int64) {
+	sort.Slice(items, func(i, j int) bool { return seq(items[i]) < seq(items[j]) })
+}
+
+// matchFilter returns whether value matches the query parameter name, which
+// matches everything when unset.
+func matchFilter(r *http.Request, name string, value string) bool {
+	filter := r.URL.Query().Get(name)
+	return filter == "" || filter == value
+}
@@ -0,0 +1,558 @@
+package koyebtest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+func newClient(t *testing.T) (*Server, *koyeb.APIClient) {
+	server := NewServer()
+	t.Cleanup(server.Close)
+
+	config := koyeb.NewConfiguration()
+	config.Servers[0].URL = server.URL
+	config.DefaultHeader["Authorization"] = "Bearer " + server.Token
+	return server, koyeb.NewAPIClient(config)
+}
+
+func createApp(t *testing.T, client *koyeb.APIClient, name string) koyeb.App {
+	res, _, err := client.AppsApi.CreateApp(context.Background()).App(koyeb.CreateApp{Name: toOpt(name)}).Execute()
+	if err != nil {
+		t.Fatalf("unable to create app: %s", err)
+	}
+	return *res.App
+}
+
+func TestServer_Unauthenticated(t *testing.T) {
+	server, _ := newClient(t)
+
+	config := koyeb.NewConfiguration()
+	config.Servers[0].URL = server.URL
+	client := koyeb.NewAPIClient(config)
+
+	_, resp, err := client.AppsApi.ListApps(context.Background()).Execute()
+	if err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a 401 without token, got %v %v", resp, err)
+	}
+}
+
+func TestServer_AppLifecycle(t *testing.T) {
+	_, client := newClient(t)
+	ctx := context.Background()
+
+	app := createApp(t, client, "my-app")
+	if app.GetStatus() != koyeb.APPSTATUS_STARTING {
+		t.Fatalf("expected a new app to be starting, got %s", app.GetStatus())
+	}
+	if len(app.Domains) != 1 || app.Domains[0].GetType() != koyeb.DOMAINTYPE_AUTOASSIGNED {
+		t.Fatalf("expected the app to have an automatic domain, got %v", app.Domains)
+	}
+
+	res, _, err := client.AppsApi.GetApp(ctx, app.GetId()).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.App.GetStatus() != koyeb.APPSTATUS_HEALTHY {
+		t.Fatalf("expected the app to become healthy, got %s", res.App.GetStatus())
+	}
+
+	_, _, err = client.AppsApi.CreateApp(ctx).App(koyeb.CreateApp{Name: toOpt("my-app")}).Execute()
+	if err == nil {
+		t.Fatal("expected an error when creating an app with a duplicate name")
+	}
+
+	if _, _, err := client.AppsApi.DeleteApp(ctx, app.GetId()).Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []koyeb.AppStatus{koyeb.APPSTATUS_DELETED} {
+		res, _, err := client.AppsApi.GetApp(ctx, app.GetId()).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.App.GetStatus() != expected {
+			t.Fatalf("expected status %s, got %s", expected, res.App.GetStatus())
+		}
+	}
+
+	_, resp, err := client.AppsApi.GetApp(ctx, app.GetId()).Execute()
+	if err == nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a 404 for a deleted app, got %v %v", resp, err)
+	}
+}
+
+func TestServer_ListPagination(t *testing.T) {
+	_, client := newClient(t)
+
+	for _, name := range []string{"app-1", "app-2", "app-3"} {
+		createApp(t, client, name)
+	}
+
+	res, _, err := client.AppsApi.ListApps(context.Background()).Limit("2").Offset("1").Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetCount() != 3 || len(res.Apps) != 2 || res.Apps[0].GetName() != "app-2" || res.GetHasNext() {
+		t.Fatalf("unexpected page: count=%d apps=%d has_next=%v", res.GetCount(), len(res.Apps), res.GetHasNext())
+	}
+
+	res, _, err = client.AppsApi.ListApps(context.Background()).Name("app-3").Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Apps) != 1 || res.Apps[0].GetName() != "app-3" {
+		t.Fatalf("expected to filter apps by name, got %v", res.Apps)
+	}
+}
+
+func TestServer_ServiceDeployment(t *testing.T) {
+	server, client := newClient(t)
+	ctx := context.Background()
+
+	app := createApp(t, client, "my-app")
+	definition := koyeb.DeploymentDefinition{
+		Name:    toOpt("main"),
+		Regions: []string{"fra", "was"},
+		Ports:   []koyeb.DeploymentPort{{Port: toOpt(int64(8000)), Protocol: toOpt("http")}},
+		Env:     []koyeb.DeploymentEnv{{Key: toOpt("KEY"), Value: toOpt("value")}},
+		Docker:  &koyeb.DockerSource{Image: toOpt("koyeb/demo")},
+	}
+
+	res, _, err := client.ServicesApi.CreateService(ctx).Service(koyeb.CreateService{
+		AppId:      app.Id,
+		Definition: &definition,
+	}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := res.Service
+
+	expected := []koyeb.DeploymentStatus{koyeb.DEPLOYMENTSTATUS_STARTING, koyeb.DEPLOYMENTSTATUS_HEALTHY}
+	for _, status := range expected {
+		res, _, err := client.DeploymentsApi.GetDeployment(ctx, service.GetLatestDeploymentId()).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Deployment.GetStatus() != status {
+			t.Fatalf("expected deployment status %s, got %s", status, res.Deployment.GetStatus())
+		}
+	}
+
+	deployment, _ := server.Deployment(service.GetLatestDeploymentId())
+	got := deployment.Definition
+	if got.GetType() != koyeb.DEPLOYMENTDEFINITIONTYPE_WEB {
+		t.Fatalf("expected the default type to be set, got %s", got.GetType())
+	}
+	if scopes := got.Env[0].Scopes; len(scopes) != 2 || scopes[0] != "region:fra" || scopes[1] != "region:was" {
+		t.Fatalf("expected the env scopes to be expanded, got %v", scopes)
+	}
+	if len(got.HealthChecks) != 1 || got.HealthChecks[0].Tcp.GetPort() != 8000 {
+		t.Fatalf("expected a default TCP health check, got %v", got.HealthChecks)
+	}
+
+	serviceRes, _, err := client.ServicesApi.GetService(ctx, service.GetId()).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serviceRes.Service.GetStatus() != koyeb.SERVICESTATUS_HEALTHY || serviceRes.Service.GetActiveDeploymentId() != deployment.GetId() {
+		t.Fatalf("expected the service to be healthy with an active deployment, got %v", serviceRes.Service)
+	}
+	if messages := serviceRes.Service.GetMessages(); len(messages) != 1 || messages[0] != "Service is healthy" {
+		t.Fatalf("expected the service status message, got %v", messages)
+	}
+
+	// A failed update leaves the service degraded
+	updateRes, _, err := client.ServicesApi.UpdateService(ctx, service.GetId()).Service(koyeb.UpdateService{Definition: &definition}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetDeploymentStatus(updateRes.Service.GetLatestDeploymentId(), koyeb.DEPLOYMENTSTATUS_ERROR, "Build failed")
+
+	serviceRes, _, err = client.ServicesApi.GetService(ctx, service.GetId()).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serviceRes.Service.GetStatus() != koyeb.SERVICESTATUS_DEGRADED || serviceRes.Service.GetMessages()[0] != "Build failed" {
+		t.Fatalf("expected the service to be degraded by the failed build, got %s %v", serviceRes.Service.GetStatus(), serviceRes.Service.GetMessages())
+	}
+
+	listRes, _, err := client.ServicesApi.ListServices(ctx).AppId(app.GetId()).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listRes.Services) != 1 {
+		t.Fatalf("expected 1 service, got %d", len(listRes.Services))
+	}
+}
+
+func TestServer_ServiceValidation(t *testing.T) {
+	_, client := newClient(t)
+
+	_, _, err := client.ServicesApi.CreateService(context.Background()).Service(koyeb.CreateService{
+		AppId:      toOpt("unknown"),
+		Definition: &koyeb.DeploymentDefinition{Name: toOpt("main")},
+	}).Execute()
+	if err == nil {
+		t.Fatal("expected an error for an unknown app")
+	}
+
+	apiErr, ok := err.(*koyeb.GenericOpenAPIError)
+	if !ok {
+		t.Fatalf("expected an API error, got %T", err)
+	}
+	model, ok := apiErr.Model().(koyeb.ErrorWithFields)
+	if !ok || len(model.Fields) != 1 || model.Fields[0].GetField() != "app_id" {
+		t.Fatalf("expected a field error on app_id, got %v", apiErr.Model())
+	}
+}
+
+func TestServer_Secrets(t *testing.T) {
+	_, client := newClient(t)
+	ctx := context.Background()
+
+	res, _, err := client.SecretsApi.CreateSecret(ctx).Secret(koyeb.CreateSecret{
+		Name:  toOpt("my-secret"),
+		Value: toOpt("s3cr3t"),
+	}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Secret.GetValue() == "s3cr3t" {
+		t.Fatal("expected the secret value not to be returned")
+	}
+
+	_, resp, _ := client.SecretsApi.RevealSecret(ctx, res.Secret.GetId()).Body(map[string]interface{}{}).Execute()
+	if resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("unable to reveal secret: %v", resp)
+	}
+
+	if _, _, err := client.SecretsApi.DeleteSecret(ctx, res.Secret.GetId()).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	_, resp, err = client.SecretsApi.GetSecret(ctx, res.Secret.GetId()).Execute()
+	if err == nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a 404 for a deleted secret, got %v %v", resp, err)
+	}
+}
+
+func TestServer_Volumes(t *testing.T) {
+	_, client := newClient(t)
+	ctx := context.Background()
+
+	res, _, err := client.PersistentVolumesApi.CreatePersistentVolume(ctx).Body(koyeb.CreatePersistentVolumeRequest{
+		Name:    toOpt("my-volume"),
+		Region:  toOpt("fra"),
+		MaxSize: toOpt(int64(10)),
+	}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	volume := res.Volume
+	if volume.GetStatus() != koyeb.PERSISTENTVOLUMESTATUS_DETACHED {
+		t.Fatalf("expected a new volume to be detached, got %s", volume.GetStatus())
+	}
+
+	_, _, err = client.PersistentVolumesApi.UpdatePersistentVolume(ctx, volume.GetId()).Body(koyeb.UpdatePersistentVolumeRequest{
+		MaxSize: toOpt(int64(5)),
+	}).Execute()
+	if err == nil {
+		t.Fatal("expected an error when shrinking a volume")
+	}
+
+	if _, _, err := client.PersistentVolumesApi.DeletePersistentVolume(ctx, volume.GetId()).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	getRes, _, err := client.PersistentVolumesApi.GetPersistentVolume(ctx, volume.GetId()).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getRes.Volume.GetStatus() != koyeb.PERSISTENTVOLUMESTATUS_DELETED {
+		t.Fatalf("expected the volume to be deleted, got %s", getRes.Volume.GetStatus())
+	}
+	_, resp, err := client.PersistentVolumesApi.GetPersistentVolume(ctx, volume.GetId()).Execute()
+	if err == nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a 404 for a deleted volume, got %v %v", resp, err)
+	}
+}
+
+func TestServer_PauseResume(t *testing.T) {
+	_, client := newClient(t)
+	ctx := context.Background()
+
+	app := createApp(t, client, "my-app")
+	res, _, err := client.ServicesApi.CreateService(ctx).Service(koyeb.CreateService{
+		AppId: app.Id,
+		Definition: &koyeb.DeploymentDefinition{
+			Name:   toOpt("main"),
+			Docker: &koyeb.DockerSource{Image: toOpt("koyeb/demo")},
+		},
+	}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := res.Service.GetId()
+
+	expectStatus := func(expected koyeb.ServiceStatus) {
+		t.Helper()
+		res, _, err := client.ServicesApi.GetService(ctx, id).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Service.GetStatus() != expected {
+			t.Fatalf("expected service status %s, got %s", expected, res.Service.GetStatus())
+		}
+	}
+
+	if _, _, err := client.ServicesApi.PauseService(ctx, id).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(koyeb.SERVICESTATUS_PAUSED)
+	expectStatus(koyeb.SERVICESTATUS_PAUSED)
+
+	if _, _, err := client.ServicesApi.PauseService(ctx, id).Execute(); err == nil {
+		t.Fatal("expected an error when pausing a paused service")
+	}
+
+	if _, _, err := client.ServicesApi.ResumeService(ctx, id).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(koyeb.SERVICESTATUS_HEALTHY)
+}
+
+func TestServer_Redeploy(t *testing.T) {
+	_, client := newClient(t)
+	ctx := context.Background()
+
+	app := createApp(t, client, "my-app")
+	res, _, err := client.ServicesApi.CreateService(ctx).Service(koyeb.CreateService{
+		AppId: app.Id,
+		Definition: &koyeb.DeploymentDefinition{
+			Name: toOpt("main"),
+			Git:  &koyeb.GitSource{Repository: toOpt("github.com/koyeb/example-flask"), Branch: toOpt("main")},
+		},
+	}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redeployRes, _, err := client.ServicesApi.ReDeploy(ctx, res.Service.GetId()).Info(koyeb.RedeployRequestInfo{Sha: toOpt("2f2a1c1")}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment := redeployRes.Deployment
+	if deployment.GetId() == res.Service.GetLatestDeploymentId() || deployment.GetParentId() != res.Service.GetLatestDeploymentId() {
+		t.Fatalf("expected a new deployment, got %s", deployment.GetId())
+	}
+	if deployment.ProvisioningInfo.GetSha() != "2f2a1c1" || deployment.Definition.Git.GetBranch() != "main" {
+		t.Fatalf("expected the requested commit of the branch to be deployed, got %v", deployment.ProvisioningInfo)
+	}
+}
+
+func TestServer_Archives(t *testing.T) {
+	server, client := newClient(t)
+	ctx := context.Background()
+
+	app := createApp(t, client, "my-app")
+	res, _, err := client.ArchivesApi.CreateArchive(ctx).Archive(koyeb.CreateArchive{Size: toOpt("7")}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	archiveID := res.Archive.GetId()
+
+	createService := func() error {
+		_, _, err := client.ServicesApi.CreateService(ctx).Service(koyeb.CreateService{
+			AppId: app.Id,
+			Definition: &koyeb.DeploymentDefinition{
+				Name:    toOpt("main"),
+				Archive: &koyeb.ArchiveSource{Id: toOpt(archiveID)},
+			},
+		}).Execute()
+		return err
+	}
+	if err := createService(); err == nil {
+		t.Fatal("expected an error when the archive is not uploaded")
+	}
+
+	upload := func(content string) int {
+		req, err := http.NewRequest(http.MethodPut, res.Archive.GetUploadUrl(), strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := upload("too long content"); status != http.StatusBadRequest {
+		t.Fatalf("expected an upload of the wrong size to be rejected, got %d", status)
+	}
+	if status := upload("content"); status != http.StatusOK {
+		t.Fatalf("expected the upload to succeed without token, got %d", status)
+	}
+	if content, ok := server.ArchiveContent(archiveID); !ok || string(content) != "content" {
+		t.Fatalf("unexpected archive content %q", content)
+	}
+
+	if err := createService(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServer_Databases(t *testing.T) {
+	_, client := newClient(t)
+	ctx := context.Background()
+	app := createApp(t, client, "my-app")
+
+	definition := &koyeb.DeploymentDefinition{
+		Name: toOpt("my-db"),
+		Type: koyeb.DEPLOYMENTDEFINITIONTYPE_DATABASE.Ptr(),
+		Database: &koyeb.DatabaseSource{NeonPostgres: &koyeb.NeonPostgresDatabase{
+			PgVersion: toOpt(int64(16)),
+			Region:    toOpt("was"),
+			Roles:     []koyeb.NeonPostgresDatabaseNeonRole{{Name: toOpt("admin"), Secret: toOpt("my-db-admin")}},
+			Databases: []koyeb.NeonPostgresDatabaseNeonDatabase{{Name: toOpt("orders"), Owner: toOpt("unknown")}},
+		}},
+	}
+	_, _, err := client.ServicesApi.CreateService(ctx).Service(koyeb.CreateService{
+		AppId:      app.Id,
+		Definition: definition,
+	}).Execute()
+	if err == nil {
+		t.Fatal("expected an error for a database owned by an unknown role")
+	}
+
+	definition.Database.NeonPostgres.Databases[0].Owner = toOpt("admin")
+	res, _, err := client.ServicesApi.CreateService(ctx).Service(koyeb.CreateService{
+		AppId:      app.Id,
+		Definition: definition,
+	}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deployment, _, err := client.DeploymentsApi.GetDeployment(ctx, res.Service.GetLatestDeploymentId()).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := deployment.Deployment.GetDatabaseInfo().NeonPostgres
+	if info.GetServerHost() == "" || len(info.Roles) != 1 {
+		t.Fatalf("expected the database information to be set, got %v", info)
+	}
+
+	secret, _, err := client.SecretsApi.RevealSecret(ctx, info.Roles[0].GetSecretId()).Body(map[string]interface{}{}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Value["username"] != "admin" || secret.Value["password"] == "" {
+		t.Fatalf("expected the role password to be revealed, got %v", secret.Value)
+	}
+}
+
+func TestServer_DryRun(t *testing.T) {
+	_, client := newClient(t)
+	ctx := context.Background()
+
+	app := createApp(t, client, "my-app")
+	definition := koyeb.DeploymentDefinition{
+		Name:          toOpt("main"),
+		Docker:        &koyeb.DockerSource{Image: toOpt("koyeb/demo")},
+		InstanceTypes: []koyeb.DeploymentInstanceType{{Type: toOpt("huge")}},
+	}
+
+	_, _, err := client.ServicesApi.CreateService(ctx).DryRun(true).Service(koyeb.CreateService{
+		AppId:      app.Id,
+		Definition: &definition,
+	}).Execute()
+	if err == nil {
+		t.Fatal("expected an error for an unknown instance type")
+	}
+
+	definition.InstanceTypes[0].Type = toOpt("nano")
+	_, _, err = client.ServicesApi.CreateService(ctx).DryRun(true).Service(koyeb.CreateService{
+		AppId:      app.Id,
+		Definition: &definition,
+	}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, _, err := client.ServicesApi.ListServices(ctx).AppId(app.GetId()).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Services) != 0 {
+		t.Fatalf("expected the dry-run not to create the service, got %d services", len(res.Services))
+	}
+}
+
+func TestServer_Instances(t *testing.T) {
+	server, client := newClient(t)
+	ctx := context.Background()
+
+	app := createApp(t, client, "my-app")
+	res, _, err := client.ServicesApi.CreateService(ctx).Service(koyeb.CreateService{
+		AppId: app.Id,
+		Definition: &koyeb.DeploymentDefinition{
+			Name:     toOpt("main"),
+			Docker:   &koyeb.DockerSource{Image: toOpt("koyeb/demo")},
+			Regions:  []string{"fra"},
+			Scalings: []koyeb.DeploymentScaling{{Min: toOpt(int64(2)), Max: toOpt(int64(2))}},
+		},
+	}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deploymentID := res.Service.GetLatestDeploymentId()
+
+	// The instances are started once the deployment is healthy
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.DeploymentsApi.GetDeployment(ctx, deploymentID).Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rds, _, err := client.RegionalDeploymentsApi.ListRegionalDeployments(ctx).DeploymentId(deploymentID).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rds.RegionalDeployments) != 1 || rds.RegionalDeployments[0].GetRegion() != "fra" || rds.RegionalDeployments[0].GetStatus() != koyeb.REGIONALDEPLOYMENTSTATUS_HEALTHY {
+		t.Fatalf("expected a healthy regional deployment in fra, got %+v", rds.RegionalDeployments)
+	}
+
+	instances, _, err := client.InstancesApi.ListInstances(ctx).DeploymentId(deploymentID).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances.Instances) != 2 {
+		t.Fatalf("expected the minimum number of instances, got %d", len(instances.Instances))
+	}
+
+	replacement, ok := server.RestartInstance(instances.Instances[0].GetId())
+	if !ok || replacement.GetReplicaIndex() != instances.Instances[0].GetReplicaIndex() {
+		t.Fatal("expected the instance to be replaced with the same replica index")
+	}
+	instances, _, err = client.InstancesApi.ListInstances(ctx).DeploymentId(deploymentID).Statuses([]string{string(koyeb.INSTANCESTATUS_HEALTHY)}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances.Instances) != 2 {
+		t.Fatalf("expected 2 healthy instances, got %d", len(instances.Instances))
+	}
+
+	if _, _, err := client.ServicesApi.PauseService(ctx, res.Service.GetId()).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	instances, _, err = client.InstancesApi.ListInstances(ctx).ServiceId(res.Service.GetId()).Statuses([]string{string(koyeb.INSTANCESTATUS_HEALTHY)}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances.Instances) != 0 {
+		t.Fatalf("expected the instances of a paused service to be stopped, got %d", len(instances.Instances))
+	}
+}
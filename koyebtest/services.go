@@ -0,0 +1,740 @@
+package koyebtest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+	"golang.org/x/exp/slices"
+)
+
+const defaultRegion = "was"
+
+// instanceTypes are the instance types accepted by the fake API.
+var instanceTypes = []string{
+	"free",
+	"eco-nano", "eco-micro", "eco-small", "eco-medium", "eco-large", "eco-xlarge", "eco-2xlarge",
+	"nano", "micro", "small", "medium", "large", "xlarge", "2xlarge", "3xlarge", "4xlarge", "5xlarge",
+}
+
+type serviceEntry struct {
+	lifecycle
+	seq      int64
+	service  koyeb.Service
+	deleting bool
+	paused   bool
+}
+
+type deploymentEntry struct {
+	lifecycle
+	seq        int64
+	deployment koyeb.Deployment
+}
+
+// Service returns the service with the given ID.
+func (s *Server) Service(id string) (koyeb.Service, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.services[id]
+	if !ok {
+		return koyeb.Service{}, false
+	}
+	s.refreshServiceStatus(entry)
+	return entry.service, true
+}
+
+// Deployment returns the deployment with the given ID.
+func (s *Server) Deployment(id string) (koyeb.Deployment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.deployments[id]
+	if !ok {
+		return koyeb.Deployment{}, false
+	}
+	return entry.deployment, true
+}
+
+// SetDeploymentStatus forces the status of a deployment, for example to
+// simulate a failed build. The deployment stays in this status.
+func (s *Server) SetDeploymentStatus(id string, status koyeb.DeploymentStatus, messages ...string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.deployments[id]
+	if !ok {
+		return false
+	}
+	entry.then()
+	s.setDeploymentStatus(entry, status)
+	entry.deployment.Messages = append([]string{}, messages...)
+	return true
+}
+
+func (s *Server) handleServices(w http.ResponseWriter, r *http.Request, id string, action string) {
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		s.listServices(w, r)
+	case id == "" && r.Method == http.MethodPost:
+		s.createService(w, r)
+	case action == "" && r.Method == http.MethodGet:
+		s.getService(w, id)
+	case action == "" && (r.Method == http.MethodPut || r.Method == http.MethodPatch):
+		s.updateService(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		s.deleteService(w, id)
+	case action == "pause" && r.Method == http.MethodPost:
+		s.pauseService(w, id)
+	case action == "resume" && r.Method == http.MethodPost:
+		s.resumeService(w, id)
+	case action == "redeploy" && r.Method == http.MethodPost:
+		s.redeployService(w, r, id)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) handleDeployments(w http.ResponseWriter, r *http.Request, id string, action string) {
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		s.listDeployments(w, r)
+	case action == "" && r.Method == http.MethodGet:
+		s.getDeployment(w, id)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) createService(w http.ResponseWriter, r *http.Request) {
+	var req koyeb.CreateService
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	app, ok := s.apps[req.GetAppId()]
+	if !ok || app.app.GetStatus() == koyeb.APPSTATUS_DELETING {
+		writeFieldError(w, "app_id", "app not found")
+		return
+	}
+	if !s.validateDefinition(w, req.Definition) {
+		return
+	}
+	for _, entry := range s.services {
+		if entry.service.GetAppId() == req.GetAppId() && entry.service.GetName() == req.Definition.GetName() {
+			writeFieldError(w, "definition.name", "already exists")
+			return
+		}
+	}
+	if isDryRun(r) {
+		writeJSON(w, http.StatusOK, koyeb.CreateServiceReply{Service: &koyeb.Service{
+			Name:  req.Definition.Name,
+			AppId: req.AppId,
+		}})
+		return
+	}
+
+	definition := normalizeDefinition(req.Definition)
+	entry := &serviceEntry{
+		seq: s.nextSeq(),
+		service: koyeb.Service{
+			Id:                 toOpt(newID()),
+			CreatedAt:          now(),
+			UpdatedAt:          now(),
+			Name:               definition.Name,
+			Type:               koyeb.ServiceType(definition.GetType()).Ptr(),
+			OrganizationId:     toOpt(s.OrganizationID),
+			AppId:              req.AppId,
+			Status:             koyeb.SERVICESTATUS_STARTING.Ptr(),
+			Messages:           []string{},
+			Version:            toOpt("1"),
+			ActiveDeploymentId: toOpt(""),
+		},
+	}
+	s.services[entry.service.GetId()] = entry
+	s.newDeployment(entry, definition)
+
+	writeJSON(w, http.StatusOK, koyeb.CreateServiceReply{Service: &entry.service})
+}
+
+func (s *Server) getService(w http.ResponseWriter, id string) {
+	entry, ok := s.services[id]
+	if !ok {
+		notFound(w, "service")
+		return
+	}
+
+	if status, ok := entry.advance(); ok {
+		entry.service.Status = koyeb.ServiceStatus(status).Ptr()
+		if status == string(koyeb.SERVICESTATUS_DELETED) {
+			entry.service.TerminatedAt = now()
+			s.removeService(id)
+		}
+	} else if !entry.deleting && !entry.paused {
+		// The service status follows its latest deployment
+		if deployment, ok := s.deployments[entry.service.GetLatestDeploymentId()]; ok {
+			s.advanceDeployment(deployment)
+		}
+		s.refreshServiceStatus(entry)
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.GetServiceReply{Service: &entry.service})
+}
+
+func (s *Server) listServices(w http.ResponseWriter, r *http.Request) {
+	types := r.URL.Query()["types"]
+
+	entries := []*serviceEntry{}
+	for _, entry := range s.services {
+		if !matchFilter(r, "app_id", entry.service.GetAppId()) || !matchFilter(r, "name", entry.service.GetName()) {
+			continue
+		}
+		if len(types) > 0 && !slices.Contains(types, string(entry.service.GetType())) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	entries, p, ok := paginate(w, r, entries, func(e *serviceEntry) int64 { return e.seq })
+	if !ok {
+		return
+	}
+
+	services := []koyeb.ServiceListItem{}
+	for _, entry := range entries {
+		s.refreshServiceStatus(entry)
+		service := entry.service
+		services = append(services, koyeb.ServiceListItem{
+			Id:                 service.Id,
+			Name:               service.Name,
+			Type:               service.Type,
+			OrganizationId:     service.OrganizationId,
+			AppId:              service.AppId,
+			UpdatedAt:          service.UpdatedAt,
+			CreatedAt:          service.CreatedAt,
+			Status:             service.Status,
+			Messages:           service.Messages,
+			Version:            service.Version,
+			ActiveDeploymentId: service.ActiveDeploymentId,
+			LatestDeploymentId: service.LatestDeploymentId,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.ListServicesReply{
+		Services: services,
+		Limit:    toOpt(p.Limit),
+		Offset:   toOpt(p.Offset),
+		Count:    toOpt(p.Count),
+		HasNext:  toOpt(p.HasNext),
+	})
+}
+
+func (s *Server) updateService(w http.ResponseWriter, r *http.Request, id string) {
+	entry, ok := s.services[id]
+	if !ok || entry.deleting {
+		notFound(w, "service")
+		return
+	}
+
+	var req koyeb.UpdateService
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if !s.validateDefinition(w, req.Definition) {
+		return
+	}
+	if req.Definition.GetName() != entry.service.GetName() {
+		writeFieldError(w, "definition.name", "can not be changed")
+		return
+	}
+	if isDryRun(r) {
+		writeJSON(w, http.StatusOK, koyeb.UpdateServiceReply{Service: &entry.service})
+		return
+	}
+
+	s.newDeployment(entry, req.Definition)
+	s.refreshServiceStatus(entry)
+
+	writeJSON(w, http.StatusOK, koyeb.UpdateServiceReply{Service: &entry.service})
+}
+
+func (s *Server) pauseService(w http.ResponseWriter, id string) {
+	entry, ok := s.services[id]
+	if !ok || entry.deleting {
+		notFound(w, "service")
+		return
+	}
+	if entry.paused {
+		writeError(w, http.StatusBadRequest, "invalid_argument", "Service is already paused")
+		return
+	}
+
+	entry.paused = true
+	entry.service.Status = koyeb.SERVICESTATUS_PAUSING.Ptr()
+	entry.service.PausedAt = now()
+	entry.then(string(koyeb.SERVICESTATUS_PAUSED))
+	s.pauseInstances(entry, true)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Server) resumeService(w http.ResponseWriter, id string) {
+	entry, ok := s.services[id]
+	if !ok || entry.deleting {
+		notFound(w, "service")
+		return
+	}
+	if !entry.paused {
+		writeError(w, http.StatusBadRequest, "invalid_argument", "Service is not paused")
+		return
+	}
+
+	entry.paused = false
+	entry.service.Status = koyeb.SERVICESTATUS_RESUMING.Ptr()
+	entry.service.ResumedAt = now()
+	entry.then(string(koyeb.SERVICESTATUS_HEALTHY))
+	s.pauseInstances(entry, false)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// redeployService creates a new deployment with the definition of the latest
+// deployment, building the requested git commit if any.
+func (s *Server) redeployService(w http.ResponseWriter, r *http.Request, id string) {
+	entry, ok := s.services[id]
+	if !ok || entry.deleting {
+		notFound(w, "service")
+		return
+	}
+
+	var req koyeb.RedeployRequestInfo
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	latest, ok := s.deployments[entry.service.GetLatestDeploymentId()]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_argument", "Service has no deployment")
+		return
+	}
+
+	definition := latest.deployment.Definition
+	if req.Sha != nil && definition.Git == nil {
+		writeFieldError(w, "sha", "can only be set for services deployed from git")
+		return
+	}
+
+	deployment := s.newDeployment(entry, definition)
+	if req.Sha != nil {
+		deployment.deployment.ProvisioningInfo.Sha = req.Sha
+	}
+	s.refreshServiceStatus(entry)
+
+	writeJSON(w, http.StatusOK, koyeb.RedeployReply{Deployment: &deployment.deployment})
+}
+
+func (s *Server) deleteService(w http.ResponseWriter, id string) {
+	entry, ok := s.services[id]
+	if !ok {
+		notFound(w, "service")
+		return
+	}
+
+	s.markServiceDeleting(entry)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Server) markServiceDeleting(entry *serviceEntry) {
+	if entry.deleting {
+		return
+	}
+	entry.deleting = true
+	entry.service.Status = koyeb.SERVICESTATUS_DELETING.Ptr()
+	entry.then(string(koyeb.SERVICESTATUS_DELETED))
+}
+
+// removeService removes the service, its deployments and their instances,
+// and detaches its volumes.
+func (s *Server) removeService(id string) {
+	for deploymentID, deployment := range s.deployments {
+		if deployment.deployment.GetServiceId() == id {
+			delete(s.deployments, deploymentID)
+		}
+	}
+	s.removeInstances(id)
+	s.attachVolumes(id, nil)
+	delete(s.services, id)
+}
+
+// refreshServiceStatus sets the service status from the status of its
+// deployments.
+func (s *Server) refreshServiceStatus(entry *serviceEntry) {
+	// Deleted, paused and resuming services have their own lifecycle
+	if entry.deleting || entry.paused || len(entry.next) > 0 {
+		return
+	}
+
+	active := entry.service.GetActiveDeploymentId() != ""
+	status := koyeb.SERVICESTATUS_STARTING
+	var messages []string
+
+	if latest, ok := s.deployments[entry.service.GetLatestDeploymentId()]; ok {
+		switch latest.deployment.GetStatus() {
+		case koyeb.DEPLOYMENTSTATUS_HEALTHY, koyeb.DEPLOYMENTSTATUS_SLEEPING:
+			status = koyeb.SERVICESTATUS_HEALTHY
+		case koyeb.DEPLOYMENTSTATUS_ERROR, koyeb.DEPLOYMENTSTATUS_UNHEALTHY, koyeb.DEPLOYMENTSTATUS_CANCELED:
+			status = koyeb.SERVICESTATUS_UNHEALTHY
+			if active {
+				status = koyeb.SERVICESTATUS_DEGRADED
+			}
+			messages = latest.deployment.Messages
+		default:
+			if active {
+				status = koyeb.SERVICESTATUS_HEALTHY
+			}
+		}
+	}
+
+	if len(messages) == 0 {
+		messages = []string{"Service is " + strings.ToLower(string(status))}
+	}
+	entry.service.Status = status.Ptr()
+	entry.service.Messages = messages
+}
+
+func (s *Server) validateDefinition(w http.ResponseWriter, definition *koyeb.DeploymentDefinition) bool {
+	switch {
+	case definition == nil:
+		writeFieldError(w, "definition", "is required")
+	case definition.GetName() == "":
+		writeFieldError(w, "definition.name", "is required")
+	case definition.Docker == nil && definition.Git == nil && definition.Archive == nil && definition.Database == nil:
+		writeFieldError(w, "definition", "a docker, git or archive source is required")
+	case definition.Database != nil:
+		return s.validateDatabase(w, definition)
+	case definition.Archive != nil && s.archives[definition.Archive.GetId()] == nil:
+		writeFieldError(w, "definition.archive.id", "archive not found")
+	case definition.Archive != nil && s.archives[definition.Archive.GetId()].content == nil:
+		writeFieldError(w, "definition.archive.id", "archive not uploaded")
+	case definition.GetType() == koyeb.DEPLOYMENTDEFINITIONTYPE_WORKER && definition.Strategy.GetType() == koyeb.DEPLOYMENTSTRATEGYTYPE_BLUE_GREEN:
+		writeFieldError(w, "definition.strategy.type", "blue-green deployments are not supported by worker services")
+	case definition.Git != nil && countSet(definition.Git.GetBranch(), definition.Git.GetTag(), definition.Git.GetSha()) != 1:
+		writeFieldError(w, "definition.git", "exactly one of branch, tag or sha must be set")
+	default:
+		if !validateScopes(w, definition) {
+			return false
+		}
+		for i, instanceType := range definition.InstanceTypes {
+			if !slices.Contains(instanceTypes, instanceType.GetType()) {
+				writeFieldError(w, "definition.instance_types."+strconv.Itoa(i)+".type", "unknown instance type "+instanceType.GetType())
+				return false
+			}
+		}
+		for i, scaling := range definition.Scalings {
+			field := "definition.scalings." + strconv.Itoa(i)
+			switch {
+			case scaling.GetMin() > scaling.GetMax():
+				writeFieldError(w, field+".min", "must be lower than or equal to max")
+			case scaling.GetMin() == 0 && definition.GetType() == koyeb.DEPLOYMENTDEFINITIONTYPE_WORKER:
+				writeFieldError(w, field+".min", "worker services can't scale to zero")
+			case hasSleepIdleDelay(scaling) && scaling.GetMin() != 0:
+				writeFieldError(w, field+".targets", "sleep_idle_delay requires min to be 0")
+			default:
+				continue
+			}
+			return false
+		}
+		for i, volume := range definition.Volumes {
+			if _, ok := s.volumes[volume.GetId()]; !ok {
+				writeFieldError(w, "definition.volumes."+strconv.Itoa(i)+".id", "volume not found")
+				return false
+			}
+		}
+		paths := map[string]bool{}
+		for i, file := range definition.FileMounts {
+			field := "definition.file_mounts." + strconv.Itoa(i)
+			switch {
+			case !strings.HasPrefix(file.GetPath(), "/"):
+				writeFieldError(w, field+".path", "must be an absolute path")
+			case paths[file.GetPath()]:
+				writeFieldError(w, field+".path", "is already mounted")
+			case file.Raw == nil && file.Secret == nil:
+				writeFieldError(w, field, "a raw or secret source is required")
+			default:
+				paths[file.GetPath()] = true
+				continue
+			}
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// validateScopes checks that the scopes of the per-region settings are
+// regions of the service in the region:<name> form.
+func validateScopes(w http.ResponseWriter, definition *koyeb.DeploymentDefinition) bool {
+	regions := definition.Regions
+	if len(regions) == 0 {
+		regions = []string{defaultRegion}
+	}
+
+	check := func(field string, scopes []string) bool {
+		for _, scope := range scopes {
+			region, ok := strings.CutPrefix(scope, "region:")
+			if !ok || !slices.Contains(regions, region) {
+				writeFieldError(w, field, "invalid scope "+scope)
+				return false
+			}
+		}
+		return true
+	}
+
+	for i, env := range definition.Env {
+		if !check("definition.env."+strconv.Itoa(i)+".scopes", env.Scopes) {
+			return false
+		}
+	}
+	for i, instanceType := range definition.InstanceTypes {
+		if !check("definition.instance_types."+strconv.Itoa(i)+".scopes", instanceType.Scopes) {
+			return false
+		}
+	}
+	for i, scaling := range definition.Scalings {
+		if !check("definition.scalings."+strconv.Itoa(i)+".scopes", scaling.Scopes) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasSleepIdleDelay(scaling koyeb.DeploymentScaling) bool {
+	for _, target := range scaling.Targets {
+		if target.SleepIdleDelay != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isDryRun returns whether the request only validates the service, without
+// creating or updating it.
+func isDryRun(r *http.Request) bool {
+	return r.URL.Query().Get("dry_run") == "true"
+}
+
+func countSet(values ...string) int {
+	n := 0
+	for _, value := range values {
+		if value != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// newDeployment creates a new deployment of the service with the definition,
+// normalized the same way the API does.
+func (s *Server) newDeployment(service *serviceEntry, definition *koyeb.DeploymentDefinition) *deploymentEntry {
+	definition = normalizeDefinition(definition)
+
+	entry := &deploymentEntry{
+		seq: s.nextSeq(),
+		deployment: koyeb.Deployment{
+			Id:              toOpt(newID()),
+			CreatedAt:       now(),
+			UpdatedAt:       now(),
+			OrganizationId:  toOpt(s.OrganizationID),
+			AppId:           service.service.AppId,
+			ServiceId:       service.service.Id,
+			ParentId:        toOpt(service.service.GetLatestDeploymentId()),
+			Status:          koyeb.DEPLOYMENTSTATUS_PENDING.Ptr(),
+			Definition:      definition,
+			Messages:        []string{},
+			Version:         toOpt("1"),
+			DeploymentGroup: toOpt("prod"),
+		},
+	}
+	if definition.Git != nil {
+		entry.deployment.ProvisioningInfo = &koyeb.DeploymentProvisioningInfo{Sha: toOpt(gitSha(definition.Git))}
+	}
+	if definition.Database != nil {
+		entry.deployment.DatabaseInfo = s.databaseInfo(service, definition)
+	}
+	entry.then(string(koyeb.DEPLOYMENTSTATUS_STARTING), string(koyeb.DEPLOYMENTSTATUS_HEALTHY))
+	s.deployments[entry.deployment.GetId()] = entry
+	s.newRegionalDeployments(&entry.deployment)
+
+	// Cancel the previous deployment if it is still in progress
+	if previous, ok := s.deployments[service.service.GetLatestDeploymentId()]; ok && len(previous.next) > 0 {
+		previous.then()
+		s.setDeploymentStatus(previous, koyeb.DEPLOYMENTSTATUS_CANCELED)
+	}
+
+	service.service.LatestDeploymentId = entry.deployment.Id
+	service.service.UpdatedAt = now()
+	s.attachVolumes(service.service.GetId(), definition)
+
+	return entry
+}
+
+// gitSha returns the commit deployed from a git source: its SHA when it is
+// pinned, or a SHA derived from the repository and the branch or tag.
+func gitSha(source *koyeb.GitSource) string {
+	if source.GetSha() != "" {
+		return source.GetSha()
+	}
+	sum := sha1.Sum([]byte(source.GetRepository() + "@" + source.GetBranch() + source.GetTag()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Server) getDeployment(w http.ResponseWriter, id string) {
+	entry, ok := s.deployments[id]
+	if !ok {
+		notFound(w, "deployment")
+		return
+	}
+
+	s.advanceDeployment(entry)
+
+	writeJSON(w, http.StatusOK, koyeb.GetDeploymentReply{Deployment: &entry.deployment})
+}
+
+func (s *Server) listDeployments(w http.ResponseWriter, r *http.Request) {
+	entries := []*deploymentEntry{}
+	for _, entry := range s.deployments {
+		if matchFilter(r, "service_id", entry.deployment.GetServiceId()) && matchFilter(r, "app_id", entry.deployment.GetAppId()) {
+			entries = append(entries, entry)
+		}
+	}
+
+	entries, p, ok := paginate(w, r, entries, func(e *deploymentEntry) int64 { return e.seq })
+	if !ok {
+		return
+	}
+
+	deployments := []koyeb.DeploymentListItem{}
+	for _, entry := range entries {
+		deployment := entry.deployment
+		deployments = append(deployments, koyeb.DeploymentListItem{
+			Id:              deployment.Id,
+			CreatedAt:       deployment.CreatedAt,
+			UpdatedAt:       deployment.UpdatedAt,
+			OrganizationId:  deployment.OrganizationId,
+			AppId:           deployment.AppId,
+			ServiceId:       deployment.ServiceId,
+			ParentId:        deployment.ParentId,
+			Status:          deployment.Status,
+			Definition:      deployment.Definition,
+			Messages:        deployment.Messages,
+			Version:         deployment.Version,
+			DeploymentGroup: deployment.DeploymentGroup,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.ListDeploymentsReply{
+		Deployments: deployments,
+		Limit:       toOpt(p.Limit),
+		Offset:      toOpt(p.Offset),
+		Count:       toOpt(p.Count),
+		HasNext:     toOpt(p.HasNext),
+	})
+}
+
+func (s *Server) advanceDeployment(entry *deploymentEntry) {
+	if status, ok := entry.advance(); ok {
+		s.setDeploymentStatus(entry, koyeb.DeploymentStatus(status))
+	}
+}
+
+// setDeploymentStatus sets the status of the deployment. A deployment
+// becoming healthy replaces the active deployment of its service.
+func (s *Server) setDeploymentStatus(entry *deploymentEntry, status koyeb.DeploymentStatus) {
+	entry.deployment.Status = status.Ptr()
+	entry.deployment.UpdatedAt = now()
+	s.setRegionalDeploymentsStatus(&entry.deployment)
+
+	switch status {
+	case koyeb.DEPLOYMENTSTATUS_STARTING:
+		entry.deployment.StartedAt = now()
+	case koyeb.DEPLOYMENTSTATUS_HEALTHY:
+		entry.deployment.SucceededAt = now()
+
+		service, ok := s.services[entry.deployment.GetServiceId()]
+		if !ok {
+			return
+		}
+		if previous, ok := s.deployments[service.service.GetActiveDeploymentId()]; ok && previous != entry {
+			previous.deployment.Status = koyeb.DEPLOYMENTSTATUS_STASHED.Ptr()
+			previous.deployment.TerminatedAt = now()
+		}
+		service.service.ActiveDeploymentId = entry.deployment.Id
+	case koyeb.DEPLOYMENTSTATUS_ERROR, koyeb.DEPLOYMENTSTATUS_CANCELED, koyeb.DEPLOYMENTSTATUS_STOPPED:
+		entry.deployment.TerminatedAt = now()
+	}
+}
+
+// normalizeDefinition returns a copy of the definition with the defaults set
+// by the API: the service type, the default region, the scopes of the
+// per-region settings expanded to the regions of the service, and a TCP
+// health check on each port without health check.
+func normalizeDefinition(definition *koyeb.DeploymentDefinition) *koyeb.DeploymentDefinition {
+	var normalized koyeb.DeploymentDefinition
+	buf, _ := json.Marshal(definition)
+	_ = json.Unmarshal(buf, &normalized)
+	definition = &normalized
+
+	if definition.GetType() == "" || definition.GetType() == koyeb.DEPLOYMENTDEFINITIONTYPE_INVALID {
+		definition.Type = koyeb.DEPLOYMENTDEFINITIONTYPE_WEB.Ptr()
+	}
+	if len(definition.Regions) == 0 {
+		definition.Regions = []string{defaultRegion}
+	}
+	if definition.Strategy.GetType() == "" || definition.Strategy.GetType() == koyeb.DEPLOYMENTSTRATEGYTYPE_INVALID {
+		definition.Strategy = &koyeb.DeploymentStrategy{Type: koyeb.DEPLOYMENTSTRATEGYTYPE_ROLLING.Ptr()}
+	}
+
+	scopes := []string{}
+	for _, region := range definition.Regions {
+		scopes = append(scopes, "region:"+region)
+	}
+	for i := range definition.Env {
+		if len(definition.Env[i].Scopes) == 0 {
+			definition.Env[i].Scopes = scopes
+		}
+	}
+	for i := range definition.InstanceTypes {
+		if len(definition.InstanceTypes[i].Scopes) == 0 {
+			definition.InstanceTypes[i].Scopes = scopes
+		}
+	}
+	for i := range definition.Scalings {
+		if len(definition.Scalings[i].Scopes) == 0 {
+			definition.Scalings[i].Scopes = scopes
+		}
+	}
+	for i := range definition.Volumes {
+		if len(definition.Volumes[i].Scopes) == 0 {
+			definition.Volumes[i].Scopes = scopes
+		}
+	}
+
+	if len(definition.HealthChecks) == 0 && definition.GetType() == koyeb.DEPLOYMENTDEFINITIONTYPE_WEB {
+		for _, port := range definition.Ports {
+			definition.HealthChecks = append(definition.HealthChecks, koyeb.DeploymentHealthCheck{
+				GracePeriod:  toOpt(int64(5)),
+				Interval:     toOpt(int64(60)),
+				RestartLimit: toOpt(int64(3)),
+				Timeout:      toOpt(int64(5)),
+				Tcp:          &koyeb.TCPHealthCheck{Port: port.Port},
+			})
+		}
+	}
+
+	return definition
+}
@@ -0,0 +1,207 @@
+package koyebtest
+
+import (
+	"net/http"
+
+	"github.com/koyeb/koyeb-api-client-go/api/v1/koyeb"
+)
+
+type volumeEntry struct {
+	lifecycle
+	seq    int64
+	volume koyeb.PersistentVolume
+}
+
+// Volume returns the volume with the given ID.
+func (s *Server) Volume(id string) (koyeb.PersistentVolume, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.volumes[id]
+	if !ok {
+		return koyeb.PersistentVolume{}, false
+	}
+	return entry.volume, true
+}
+
+func (s *Server) handleVolumes(w http.ResponseWriter, r *http.Request, id string, action string) {
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		s.listVolumes(w, r)
+	case id == "" && r.Method == http.MethodPost:
+		s.createVolume(w, r)
+	case action == "" && r.Method == http.MethodGet:
+		s.getVolume(w, id)
+	case action == "" && r.Method == http.MethodPost:
+		s.updateVolume(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		s.deleteVolume(w, id)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) createVolume(w http.ResponseWriter, r *http.Request) {
+	var req koyeb.CreatePersistentVolumeRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	name := req.GetName()
+	if name == "" {
+		writeFieldError(w, "name", "is required")
+		return
+	}
+	if req.GetRegion() == "" {
+		writeFieldError(w, "region", "is required")
+		return
+	}
+	if req.GetMaxSize() <= 0 {
+		writeFieldError(w, "max_size", "must be greater than 0")
+		return
+	}
+	for _, entry := range s.volumes {
+		if entry.volume.GetName() == name {
+			writeFieldError(w, "name", "already exists")
+			return
+		}
+	}
+
+	backingStore := req.GetVolumeType()
+	if backingStore == "" {
+		backingStore = koyeb.PERSISTENTVOLUMEBACKINGSTORE_LOCAL_BLK
+	}
+
+	entry := &volumeEntry{
+		seq: s.nextSeq(),
+		volume: koyeb.PersistentVolume{
+			Id:             toOpt(newID()),
+			Name:           toOpt(name),
+			SnapshotId:     toOpt(req.GetSnapshotId()),
+			CreatedAt:      now(),
+			UpdatedAt:      now(),
+			OrganizationId: toOpt(s.OrganizationID),
+			ServiceId:      toOpt(""),
+			Region:         toOpt(req.GetRegion()),
+			ReadOnly:       toOpt(req.GetReadOnly()),
+			MaxSize:        toOpt(req.GetMaxSize()),
+			CurSize:        toOpt(int64(0)),
+			Status:         koyeb.PERSISTENTVOLUMESTATUS_DETACHED.Ptr(),
+			BackingStore:   backingStore.Ptr(),
+		},
+	}
+	s.volumes[entry.volume.GetId()] = entry
+
+	writeJSON(w, http.StatusOK, koyeb.CreatePersistentVolumeReply{Volume: &entry.volume})
+}
+
+func (s *Server) getVolume(w http.ResponseWriter, id string) {
+	entry, ok := s.volumes[id]
+	if !ok {
+		notFound(w, "volume")
+		return
+	}
+
+	if status, ok := entry.advance(); ok {
+		entry.volume.Status = koyeb.PersistentVolumeStatus(status).Ptr()
+		if status == string(koyeb.PERSISTENTVOLUMESTATUS_DELETED) {
+			entry.volume.DeletedAt = now()
+			delete(s.volumes, id)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.GetPersistentVolumeReply{Volume: &entry.volume})
+}
+
+func (s *Server) listVolumes(w http.ResponseWriter, r *http.Request) {
+	entries := []*volumeEntry{}
+	for _, entry := range s.volumes {
+		if matchFilter(r, "name", entry.volume.GetName()) && matchFilter(r, "region", entry.volume.GetRegion()) &&
+			matchFilter(r, "service_id", entry.volume.GetServiceId()) {
+			entries = append(entries, entry)
+		}
+	}
+
+	entries, p, ok := paginate(w, r, entries, func(e *volumeEntry) int64 { return e.seq })
+	if !ok {
+		return
+	}
+
+	volumes := []koyeb.PersistentVolume{}
+	for _, entry := range entries {
+		volumes = append(volumes, entry.volume)
+	}
+
+	writeJSON(w, http.StatusOK, koyeb.ListPersistentVolumesReply{
+		Volumes: volumes,
+		Limit:   toOpt(p.Limit),
+		Offset:  toOpt(p.Offset),
+		HasNext: toOpt(p.HasNext),
+	})
+}
+
+func (s *Server) updateVolume(w http.ResponseWriter, r *http.Request, id string) {
+	entry, ok := s.volumes[id]
+	if !ok {
+		notFound(w, "volume")
+		return
+	}
+
+	var req koyeb.UpdatePersistentVolumeRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.MaxSize != nil {
+		if req.GetMaxSize() < entry.volume.GetMaxSize() {
+			writeFieldError(w, "max_size", "volumes can not be shrunk")
+			return
+		}
+		entry.volume.MaxSize = req.MaxSize
+	}
+	if req.Name != nil {
+		entry.volume.Name = req.Name
+	}
+	entry.volume.UpdatedAt = now()
+
+	writeJSON(w, http.StatusOK, koyeb.UpdatePersistentVolumeReply{Volume: &entry.volume})
+}
+
+func (s *Server) deleteVolume(w http.ResponseWriter, id string) {
+	entry, ok := s.volumes[id]
+	if !ok {
+		notFound(w, "volume")
+		return
+	}
+	if entry.volume.GetStatus() == koyeb.PERSISTENTVOLUMESTATUS_ATTACHED {
+		writeError(w, http.StatusBadRequest, "invalid_argument", "The volume is attached to a service")
+		return
+	}
+
+	entry.volume.Status = koyeb.PERSISTENTVOLUMESTATUS_DELETING.Ptr()
+	entry.then(string(koyeb.PERSISTENTVOLUMESTATUS_DELETED))
+
+	writeJSON(w, http.StatusOK, koyeb.DeletePersistentVolumeReply{Volume: &entry.volume})
+}
+
+// attachVolumes marks the volumes used by the deployment definition as
+// attached to the service, and detaches the volumes it no longer uses.
+func (s *Server) attachVolumes(serviceID string, definition *koyeb.DeploymentDefinition) {
+	used := map[string]bool{}
+	if definition != nil {
+		for _, volume := range definition.Volumes {
+			used[volume.GetId()] = true
+		}
+	}
+
+	for id, entry := range s.volumes {
+		switch {
+		case used[id]:
+			entry.volume.ServiceId = toOpt(serviceID)
+			entry.volume.Status = koyeb.PERSISTENTVOLUMESTATUS_ATTACHED.Ptr()
+		case entry.volume.GetServiceId() == serviceID:
+			entry.volume.ServiceId = toOpt("")
+			entry.volume.Status = koyeb.PERSISTENTVOLUMESTATUS_DETACHED.Ptr()
+		}
+	}
+}